@@ -0,0 +1,48 @@
+package todo
+
+import "slices"
+
+//=====================
+// ARCHIVE
+//=====================
+// Completed items can be moved out of the List into an archive, which is just another List kept in it's own
+// Store. Moving keeps the items as they are, including their IDs, so they can be restored later.
+
+// Archive will move the items with the given IDs, and their subtasks, from the List to the end of the archive. An item
+// that is already in the archive (eg.: restored by an undo) is replaced. Nothing is moved if any of the IDs doesn't
+// exist.
+func (l *List) Archive(archive *List, ids []string) error {
+	return move(l, archive, ids)
+}
+
+// Restore will move the items with the given IDs, and their subtasks, from the archive back to the end of the List.
+// An item that is already on the List is replaced. Nothing is moved if any of the IDs doesn't exist.
+func (l *List) Restore(archive *List, ids []string) error {
+	return move(archive, l, ids)
+}
+
+// move removes the items with the given IDs from src and appends them to dst, replacing copies already in dst.
+// Subtasks are moved along with their parent, so they're never left behind on src or lost.
+func move(src, dst *List, ids []string) error {
+	positions, err := src.positions(ids)
+	if err != nil {
+		return err
+	}
+	for _, pos := range positions {
+		positions = append(positions, src.descendants(pos)...)
+	}
+
+	// append in the order the items have on src, once each
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+	for _, pos := range positions {
+		it := (*src)[pos-1]
+		if existing, err := dst.Position(it.ID); err == nil {
+			(*dst)[existing-1] = it
+			continue
+		}
+		*dst = append(*dst, it)
+	}
+
+	return src.DeleteAll(ids)
+}
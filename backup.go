@@ -0,0 +1,191 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//=====================
+// BACKUPS
+//=====================
+// Before each Save, JSONStore can keep a copy of the file it's about to replace in the filename.backups directory.
+// Only the most recent copies are kept, either one per save or one per day. eg.: with a policy of 5, the list can
+// be rolled back to the state it had before any of the last 5 saves.
+// Each backup is named after the time it was taken: 20261017-153012.123456 for the ones taken on every save, and
+// 20261017 for the daily ones. A daily backup holds the list as it was before the first save of the day.
+
+// BackupPolicy configures the backups kept by JSONStore. Keep is the number of backups kept; zero disables them.
+// When Daily is set a single backup is taken per day, instead of one per save.
+type BackupPolicy struct {
+	Keep  int
+	Daily bool
+}
+
+// DefaultDailyBackups is the number of daily backups kept when the policy doesn't give one
+const DefaultDailyBackups = 7
+
+// the layouts of the names of the backups
+const (
+	backupLayout      = "20060102-150405.000000"
+	dailyBackupLayout = "20060102"
+)
+
+// ParseBackupPolicy parses a backup policy: the number of backups to keep, taken on every save, or daily[:N] to
+// keep the last N daily ones (DefaultDailyBackups when N is left out). off or 0 disables the backups.
+func ParseBackupPolicy(s string) (BackupPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "off" {
+		return BackupPolicy{}, nil
+	}
+
+	p := BackupPolicy{}
+	keep, daily := strings.CutPrefix(s, "daily")
+	if daily {
+		p.Daily = true
+		p.Keep = DefaultDailyBackups
+		if keep == "" {
+			return p, nil
+		}
+		if keep, daily = strings.CutPrefix(keep, ":"); !daily {
+			return p, fmt.Errorf("invalid backup policy %q. Use a number, daily or daily:N", s)
+		}
+	}
+
+	n, err := strconv.Atoi(keep)
+	if err != nil || n < 0 {
+		return p, fmt.Errorf("invalid backup policy %q. Use a number, daily or daily:N", s)
+	}
+	p.Keep = n
+	return p, nil
+}
+
+// String formats the policy the way ParseBackupPolicy reads it
+func (p BackupPolicy) String() string {
+	switch {
+	case p.Keep <= 0:
+		return "off"
+	case p.Daily:
+		return fmt.Sprintf("daily:%d", p.Keep)
+	}
+	return strconv.Itoa(p.Keep)
+}
+
+// Backup is a copy of the list file kept by JSONStore
+type Backup struct {
+	Name  string    // the name to restore it by. eg.: 20261017-153012.123456
+	Path  string    // the path of the copy
+	Time  time.Time // when it was taken. Daily backups are at the start of their day
+	Size  int64
+	Daily bool // it's a daily backup
+}
+
+// backupDir returns the directory the backups of the store's file are kept in
+func (s *JSONStore) backupDir() string {
+	return s.Filename + ".backups"
+}
+
+// backup will copy data, the store's file as it is on disk, to the backup directory following the store's policy,
+// and remove the backups past the number kept. Nothing is copied when the file doesn't exist yet.
+func (s *JSONStore) backup(now time.Time, data []byte) error {
+	if s.BackupPolicy.Keep <= 0 || len(data) == 0 {
+		return nil
+	}
+
+	name := now.Format(backupLayout)
+	if s.BackupPolicy.Daily {
+		name = now.Format(dailyBackupLayout)
+	}
+	path := filepath.Join(s.backupDir(), name+".json")
+
+	// the daily backup keeps the list as it was before the first save of the day
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.backupDir(), 0755); err != nil {
+		return err
+	}
+	if err := writeFile(path, data); err != nil {
+		return err
+	}
+	return s.pruneBackups()
+}
+
+// pruneBackups removes the oldest backups past the number the policy keeps. Daily backups and the ones taken on
+// every save are counted apart, so switching policies doesn't remove the other kind.
+func (s *JSONStore) pruneBackups() error {
+	all, err := s.Backups()
+	if err != nil {
+		return err
+	}
+	backups := []Backup{}
+	for _, b := range all {
+		if b.Daily == s.BackupPolicy.Daily {
+			backups = append(backups, b)
+		}
+	}
+
+	for len(backups) > s.BackupPolicy.Keep {
+		if err := os.Remove(backups[0].Path); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Backups returns the backups of the store's file, the oldest first
+func (s *JSONStore) Backups() ([]Backup, error) {
+	entries, err := os.ReadDir(s.backupDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		daily := false
+		t, err := time.ParseInLocation(backupLayout, name, time.Local)
+		if err != nil {
+			if t, err = time.ParseInLocation(dailyBackupLayout, name, time.Local); err != nil {
+				// not a backup
+				continue
+			}
+			daily = true
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Backup{Name: name, Path: filepath.Join(s.backupDir(), e.Name()), Time: t, Size: info.Size(), Daily: daily})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// LoadBackup will read the named backup into the List. Backups of older versions of the schema are upgraded like
+// the list file itself.
+func (s *JSONStore) LoadBackup(name string, l *List) error {
+	backups, err := s.Backups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		if b.Name == name {
+			return NewJSONStore(b.Path).Load(l)
+		}
+	}
+	return fmt.Errorf("backup %q does not exist", name)
+}
@@ -0,0 +1,83 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+//=====================
+// BATCH ADD
+//=====================
+
+// batchTask is a task read by AddBatch, along with it's inline metadata
+type batchTask struct {
+	task     string
+	priority string
+	due      time.Time
+	tags     []string
+}
+
+// AddBatch will add a task for every non-blank line read from r. Each line can carry inline metadata: a priority
+// before the task, eg.: (A), +project and @context tags and a due date as due:DATE, where DATE is anything ParseDate
+// accepts. eg.: (B) Pay rent +home due:+3d
+// Nothing is added if any of the lines is invalid. It returns the number of tasks added.
+func (l *List) AddBatch(r io.Reader, now time.Time) (int, error) {
+	// parse every line before adding anything
+	tasks := []batchTask{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		t, err := parseBatchLine(scanner.Text(), now)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, t := range tasks {
+		l.Add(t.task)
+		ls := *l
+		ls[len(ls)-1].Priority = t.priority
+		ls[len(ls)-1].Due = t.due
+		l.AddTags(len(ls), t.tags...)
+	}
+	return len(tasks), nil
+}
+
+// parseBatchLine splits the inline metadata off a line read by AddBatch
+func parseBatchLine(line string, now time.Time) (batchTask, error) {
+	t := batchTask{}
+	fields := strings.Fields(line)
+
+	if p, ok := priorityToken(firstField(fields)); ok {
+		t.priority = p
+		fields = fields[1:]
+	}
+
+	words := []string{}
+	for _, field := range fields {
+		if value, ok := strings.CutPrefix(field, "due:"); ok && value != "" {
+			d, err := ParseDate(value, now)
+			if err != nil {
+				return t, err
+			}
+			t.due = d
+			continue
+		}
+		words = append(words, field)
+	}
+
+	t.task, t.tags = ParseTags(strings.Join(words, " "))
+	if t.task == "" {
+		return t, fmt.Errorf("Task cannot be blank")
+	}
+	return t, nil
+}
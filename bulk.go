@@ -0,0 +1,169 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//=====================
+// BULK OPERATIONS
+//=====================
+// Bulk operations work on the IDs of the items rather than their positions, so removing some of the items doesn't
+// shift the others. They check every ID before changing anything: either all items are changed or none.
+
+// Select will resolve a selection of items into their IDs. The selection is a comma separated list of IDs,
+// numbers and ranges of top-level numbers, as accepted by Resolve. eg.: "1-5,8,2.1,a1b2c3d4". Items selected more
+// than once are only returned once.
+func (l *List) Select(sel string) ([]string, error) {
+	ids := []string{}
+	add := func(pos int) {
+		id := (*l)[pos-1].ID
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	// the positions of the top-level items, by their number
+	top := []int{}
+	for _, e := range l.View() {
+		if e.Depth == 0 {
+			top = append(top, e.Pos)
+		}
+	}
+
+	for _, part := range strings.Split(sel, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		// a range of positions. eg.: 1-5
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			first, err1 := strconv.Atoi(from)
+			last, err2 := strconv.Atoi(to)
+			if err1 != nil || err2 != nil || first > last {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+			if first <= 0 || last > len(top) {
+				return nil, fmt.Errorf("range %q is out of the list (1-%d)", part, len(top))
+			}
+			for n := first; n <= last; n++ {
+				add(top[n-1])
+			}
+			continue
+		}
+
+		pos, err := l.Resolve(part)
+		if err != nil {
+			return nil, err
+		}
+		add(pos)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no items selected")
+	}
+	return ids, nil
+}
+
+// IDs returns the IDs of the entries of the View, in order
+func (v View) IDs() []string {
+	ids := make([]string, 0, len(v))
+	for _, e := range v {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// positions will return the positions of the items with the given IDs, failing if any of them doesn't exist
+func (l *List) positions(ids []string) ([]int, error) {
+	positions := make([]int, 0, len(ids))
+	for _, id := range ids {
+		pos, err := l.Position(id)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, pos)
+	}
+	return positions, nil
+}
+
+// CompleteAll will mark every item with the given IDs as completed. Items that are already done are left as they
+// are, keeping their completion date. Items with open subtasks can only be completed along with those subtasks.
+func (l *List) CompleteAll(ids []string) error {
+	positions, err := l.positions(ids)
+	if err != nil {
+		return err
+	}
+
+	// check the subtasks before changing anything
+	for _, pos := range positions {
+		for _, sub := range l.descendants(pos) {
+			if !(*l)[sub-1].Done && !slices.Contains(positions, sub) {
+				return fmt.Errorf("%q has open subtasks", (*l)[pos-1].Task)
+			}
+		}
+	}
+
+	// complete the subtasks before their parents
+	slices.SortStableFunc(positions, func(a, b int) int {
+		return l.depth(b) - l.depth(a)
+	})
+	for _, pos := range positions {
+		if !(*l)[pos-1].Done {
+			l.Complete(pos)
+		}
+	}
+	return nil
+}
+
+// DeleteAll will remove every item with the given IDs from the List, along with their subtasks
+func (l *List) DeleteAll(ids []string) error {
+	positions, err := l.positions(ids)
+	if err != nil {
+		return err
+	}
+	ids = slices.Clone(ids)
+	for _, pos := range positions {
+		for _, sub := range l.descendants(pos) {
+			ids = append(ids, (*l)[sub-1].ID)
+		}
+	}
+
+	kept := List{}
+	for _, it := range *l {
+		if !slices.Contains(ids, it.ID) {
+			kept = append(kept, it)
+		}
+	}
+	*l = kept
+	return nil
+}
+
+// TagAll will add the tags to every item with the given IDs
+func (l *List) TagAll(ids []string, tags ...string) error {
+	positions, err := l.positions(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, pos := range positions {
+		l.AddTags(pos, tags...)
+	}
+	return nil
+}
+
+// UntagAll will remove the tags from every item with the given IDs
+func (l *List) UntagAll(ids []string, tags ...string) error {
+	positions, err := l.positions(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, pos := range positions {
+		l.RemoveTags(pos, tags...)
+	}
+	return nil
+}
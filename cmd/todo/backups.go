@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/dupakarovsky/todo"
+)
+
+//=================================
+// BACKUPS
+//=================================
+
+// defaultBackups is the backup policy used when neither -backups nor TODO_BACKUPS is set
+const defaultBackups = "5"
+
+// backupsCmd lists the backups of the list file, the most recent first
+func backupsCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		store, err := todo.OpenStore(a.storeName, a.filename)
+		if err != nil {
+			return usageError{err: err}
+		}
+		js, ok := store.(*todo.JSONStore)
+		if !ok {
+			return usageErrorf("backups are only kept by the json store")
+		}
+
+		backups, err := js.Backups()
+		if err != nil {
+			return err
+		}
+		a.listedBackups(backups)
+		if len(backups) == 0 {
+			fmt.Fprintln(a.stdout, "No backups")
+			return nil
+		}
+		for i := len(backups) - 1; i >= 0; i-- {
+			b := backups[i]
+			fmt.Fprintf(a.stdout, "%-22s  %s  %d bytes\n", b.Name, b.Time.Format(time.DateTime), b.Size)
+		}
+		return nil
+	}
+}
+
+// restoreBackup rolls the list back to the named backup. The list isn't loaded first, as a damaged list is when a
+// roll back is needed the most. When the list can be loaded the roll back is recorded in the history, so it can be
+// undone. Either way the list is backed up before it's replaced.
+func restoreBackup(a *app, name string) error {
+	store, err := a.openStore()
+	if err != nil {
+		return err
+	}
+	js, ok := store.(*todo.JSONStore)
+	if !ok {
+		return usageErrorf("backups are only kept by the json store")
+	}
+	lock, err := todo.Lock(a.filename)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	restored := todo.List{}
+	if err := js.LoadBackup(name, &restored); err != nil {
+		return err
+	}
+	a.store, a.list = store, &restored
+
+	// record the roll back, unless the list is too damaged to load
+	current := todo.List{}
+	if err := store.Load(&current); err != nil {
+		fmt.Fprintf(a.stderr, "the list couldn't be loaded (%v). The roll back won't be in the history\n", err)
+		if err := store.Save(&restored); err != nil {
+			return err
+		}
+	} else {
+		if a.history, err = todo.LoadHistory(a.historyFile()); err != nil {
+			return err
+		}
+		a.before = current
+		if err := a.save(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(a.stdout, "Rolled the list back to backup %s (%d task(s))\n", name, len(restored))
+	return nil
+}
@@ -0,0 +1,785 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dupakarovsky/todo"
+)
+
+//=================================
+// COMMANDS
+//=================================
+
+// runFunc runs a command with the arguments left after parsing it's flags
+type runFunc func(a *app, args []string) error
+
+// command describes a subcommand of the tool. setup registers the command's flags on the FlagSet and returns the
+// function that runs it, so the flag values are available to it once they're parsed.
+type command struct {
+	name    string
+	aliases []string
+	args    string // synopsis of the arguments, displayed in the help
+	summary string
+	noList  bool // the command doesn't need the list loaded, or loads it itself
+	noJSON  bool // the command has no result to print as JSON, or it's own output format, so it has no -json flag
+	setup   func(fs *flag.FlagSet) runFunc
+}
+
+// commands lists every subcommand, in the order they're displayed in the help.
+// INFO: assigned in init() as the help command refers back to the table.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "add", args: "<task>", summary: "Add a task to the list, or a subtask with -parent. Reads it from STDIN when no task is given", setup: addCmd},
+		{name: "list", aliases: []string{"ls"}, summary: "List the tasks", setup: listCmd},
+		{name: "show", args: "<id|number>", summary: "Show every detail of a task, including it's notes", setup: showCmd},
+		{name: "done", aliases: []string{"complete"}, args: "[selection]", summary: "Mark tasks as completed. eg.: todo done 1-3,5 or todo done -tag +release", setup: doneCmd},
+		{name: "reopen", aliases: []string{"uncomplete"}, args: "<id|position>", summary: "Mark a completed task as active again", setup: reopenCmd},
+		{name: "rm", aliases: []string{"del", "delete"}, args: "[selection]", summary: "Delete tasks. eg.: todo rm 2,4 or todo rm -done -older-than 30d", setup: rmCmd},
+		{name: "tag", args: "[selection] <tag>...", summary: "Tag tasks. The selection is left out when filter flags are given", setup: tagCmd},
+		{name: "edit", args: "<id|position> [new text]", summary: "Rename a task or change it's priority, due date, recurrence or tags", setup: editCmd},
+		{name: "archive", args: "[selection]", summary: "Move completed tasks to the archive. eg.: todo archive -completed-before -30d", setup: archiveCmd},
+		{name: "archived", summary: "List or search the archived tasks", setup: archivedCmd},
+		{name: "restore", args: "<selection> | -backup <name>", summary: "Move archived tasks back to the list, or roll the list back to a backup", noList: true, setup: restoreCmd},
+		{name: "import", args: "[file]", summary: "Import tasks from a todo.txt file or STDIN", setup: importCmd},
+		{name: "export", summary: "Export the tasks as todo.txt, CSV or Markdown. eg.: todo export -format csv -o todo.csv", noJSON: true, setup: exportCmd},
+		{name: "undo", summary: "Undo the last change to the list", setup: undoCmd},
+		{name: "redo", summary: "Redo the last undone change", setup: redoCmd},
+		{name: "history", summary: "Show the changes that can be undone and redone", setup: historyCmd},
+		{name: "backups", summary: "List the backups of the list, the most recent first. Roll back with todo restore -backup <name>", noList: true, setup: backupsCmd},
+		{name: "doctor", summary: "Check the list for problems and repair it, keeping a copy of the file. Use -n to only check", noList: true, setup: doctorCmd},
+		{name: "help", args: "[command]", summary: "Show the help of the tool or of a command", noList: true, noJSON: true, setup: helpCmd},
+	}
+}
+
+// lookupCommand finds a command by it's name or one of it's aliases
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// flagSet creates the FlagSet for the command. Errors and the help are written to w.
+func (c command) flagSet(w io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { c.usage(fs) }
+	return fs
+}
+
+// jsonOutput reports whether the command can print it's result as JSON
+func (c command) jsonOutput() bool {
+	return !c.noJSON
+}
+
+// usage prints the help of the command
+func (c command) usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "Usage: todo %s [flags] %s\n\n%s\n", c.name, c.args, c.summary)
+	if len(c.aliases) > 0 {
+		fmt.Fprintf(out, "Aliases: %s\n", strings.Join(c.aliases, ", "))
+	}
+
+	// only print the flags section when the command has flags
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(out, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+}
+
+// stringList is a flag that can be given more than once, collecting every value. eg.: -tag +work -tag @home
+type stringList []string
+
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+func (sl *stringList) Set(v string) error {
+	*sl = append(*sl, v)
+	return nil
+}
+
+// requireArgs returns a usageError unless exactly n arguments were given
+func requireArgs(args []string, n int, what string) error {
+	if len(args) != n {
+		return usageErrorf("expected %s", what)
+	}
+	return nil
+}
+
+// resolveAll translates the references to tasks (IDs or numbers) into their positions on the list
+func resolveAll(l *todo.List, refs []string) ([]int, error) {
+	positions := []int{}
+	for _, ref := range refs {
+		pos, err := l.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, pos)
+	}
+	return positions, nil
+}
+
+// addCmd adds a new task, with an optional priority, due date, recurrence, dependencies and notes
+func addCmd(fs *flag.FlagSet) runFunc {
+	priority := fs.String("priority", "", "Priority of the task: A-Z, 1-26 or high/medium/low")
+	due := fs.String("due", "", "Due date of the task: YYYY-MM-DD, today, tomorrow, +3d, +2w")
+	every := fs.String("every", "", "Repeat the task when it's completed: daily, weekdays, weekly[:mon,thu], every:3d or monthly[:15]")
+	parent := fs.String("parent", "", "Add the task as a subtask of another one, given by it's ID or number (e.g: 2 or 2.1)")
+	tags := &stringList{}
+	fs.Var(tags, "tag", "Tag the task. Can be repeated (e.g: -tag +project -tag @context)")
+	blockedBy := &stringList{}
+	fs.Var(blockedBy, "blocked-by", "ID or number of a task that has to be done before this one. Can be repeated")
+	notes := fs.String("notes", "", "Notes of the task. When the task is read from STDIN, the lines after the first one are the notes")
+	batch := fs.Bool("batch", false, "Add every line read from STDIN as a task, with it's priority, tags and due date inline (e.g: (A) Pay rent +home due:+3d)")
+
+	return func(a *app, args []string) error {
+		if *batch {
+			return addBatch(a, fs, args)
+		}
+
+		// call getTask() with the stdin (which implements io.Reader) and the arguments left after the flags
+		t, n, err := getTask(a.stdin, args...)
+		if err != nil {
+			return err
+		}
+		if *notes != "" {
+			if n != "" {
+				return usageErrorf("give the notes with -notes or on STDIN, not both")
+			}
+			n = *notes
+		}
+		// split the +project and @context tokens off the task
+		t, textTags := todo.ParseTags(t)
+		if t == "" {
+			return fmt.Errorf("Task cannot be blank")
+		}
+
+		// validate the priority, due date and recurrence before adding anything
+		p, err := todo.ParsePriority(*priority)
+		if err != nil {
+			return usageError{err: err}
+		}
+		d, err := todo.ParseDate(*due, a.now)
+		if err != nil {
+			return usageError{err: err}
+		}
+		r, err := todo.ParseRecurrence(*every)
+		if err != nil {
+			return usageError{err: err}
+		}
+		blockers, err := resolveAll(a.list, *blockedBy)
+		if err != nil {
+			return err
+		}
+
+		// call Add() with the string getTasks returns, or AddSubtask() when a parent is given. Then set the priority
+		// and due date of the new (last) item
+		l := a.list
+		if *parent != "" {
+			ppos, err := l.Resolve(*parent)
+			if err != nil {
+				return err
+			}
+			if err := l.AddSubtask(ppos, t); err != nil {
+				return err
+			}
+		} else {
+			l.Add(t)
+		}
+		if err := l.SetPriority(len(*l), p); err != nil {
+			return err
+		}
+		if err := l.SetDue(len(*l), d); err != nil {
+			return err
+		}
+		if err := l.SetRecurrence(len(*l), r); err != nil {
+			return err
+		}
+		if err := l.AddTags(len(*l), append(textTags, *tags...)...); err != nil {
+			return err
+		}
+		for _, b := range blockers {
+			if err := l.Block(len(*l), b); err != nil {
+				return err
+			}
+		}
+		if err := l.SetNotes(len(*l), n); err != nil {
+			return err
+		}
+
+		// save the updated list on disk.
+		return a.save()
+	}
+}
+
+// addBatch adds every line read from STDIN as a task and saves the list once
+func addBatch(a *app, fs *flag.FlagSet, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("-batch reads the tasks from STDIN. Unexpected arguments %v", args)
+	}
+	// the metadata is given on each line, so the other flags would be ambiguous
+	others := []string{}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "batch" {
+			others = append(others, "-"+f.Name)
+		}
+	})
+	if len(others) > 0 {
+		return usageErrorf("-batch can't be combined with %s. Give the priority, tags and due date on each line", strings.Join(others, ", "))
+	}
+
+	n, err := a.list.AddBatch(a.stdin, a.now)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Added %d task(s)\n", n)
+	return a.save()
+}
+
+// listCmd prints the tasks. The flags can be combined with each other
+func listCmd(fs *flag.FlagSet) runFunc {
+	verbose := fs.Bool("verbose", false, "Display verbose output")
+	sortBy := fs.String("sort", "", "Sort the listed tasks. Accepts: priority")
+	overdue := fs.Bool("overdue", false, "List overdue tasks only")
+	dueWithin := fs.String("due-within", "", "List tasks due within a span from now (e.g: 7d, 2w, 36h)")
+	blocked := fs.Bool("blocked", false, "List active tasks blocked by other open tasks only")
+	ready := fs.Bool("ready", false, "List active tasks whose dependencies are all done only")
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+
+		// build the filter from the flags
+		f, err := filter(a)
+		if err != nil {
+			return err
+		}
+
+		// build the view. Entries keep their positions on the list even when sorted or filtered.
+		view := a.list.Filter(f)
+		switch *sortBy {
+		case "":
+		case "priority":
+			view = view.SortByPriority()
+		default:
+			return usageErrorf("invalid sort %q", *sortBy)
+		}
+
+		// narrow down the view to overdue or upcoming tasks
+		if *overdue {
+			view = view.Overdue(a.now)
+		}
+		if *dueWithin != "" {
+			span, err := todo.ParseSpan(*dueWithin)
+			if err != nil {
+				return usageError{err: err}
+			}
+			view = view.DueWithin(a.now, span)
+		}
+
+		// or to the tasks that are blocked or ready to be worked on
+		if *blocked && *ready {
+			return usageErrorf("use either -blocked or -ready, not both")
+		}
+		if *blocked {
+			view = view.Blocked(a.list)
+		}
+		if *ready {
+			view = view.Ready(a.list)
+		}
+
+		a.listed(view)
+		if *verbose {
+			fmt.Fprint(a.stdout, view.Verbose(a.now))
+			return nil
+		}
+		// Print(view) uses the String() of the View, which formats the same way as the List
+		fmt.Fprint(a.stdout, view)
+		return nil
+	}
+}
+
+// showCmd prints every detail of a single task
+func showCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if err := requireArgs(args, 1, "the ID or number of the task"); err != nil {
+			return err
+		}
+		pos, err := a.list.Resolve(args[0])
+		if err != nil {
+			return err
+		}
+
+		// look the task up on the view, which knows it's number on the tree
+		for _, e := range a.list.View() {
+			if e.Pos == pos {
+				a.listed(todo.View{e})
+				fmt.Fprint(a.stdout, e.Details(a.now))
+			}
+		}
+		return nil
+	}
+}
+
+// doneCmd marks the selected tasks as completed. Tasks blocked by open tasks are refused, unless -force is given
+func doneCmd(fs *flag.FlagSet) runFunc {
+	force := fs.Bool("force", false, "Complete blocked tasks anyway, printing a warning")
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		ids, err := bulkIDs(a, args, filter)
+		if err != nil {
+			return err
+		}
+
+		// check the blockers. Blockers completed by this same command don't count
+		for _, id := range ids {
+			pos, _ := a.list.Position(id)
+			for _, b := range a.list.Blockers(pos) {
+				blocker := (*a.list)[b-1]
+				if slices.Contains(ids, blocker.ID) {
+					continue
+				}
+				task := (*a.list)[pos-1].Task
+				if !*force {
+					return fmt.Errorf("%q is blocked by %q (%s). Use -force to complete it anyway", task, blocker.Task, blocker.ID)
+				}
+				fmt.Fprintf(a.stderr, "warning: completing %q while it's blocked by %q (%s)\n", task, blocker.Task, blocker.ID)
+			}
+		}
+		// call CompleteAll() to update Done and CompletedAt fields of every task at once
+		if err := a.list.CompleteAll(ids); err != nil {
+			return err
+		}
+		// save the updated list on disk.
+		return a.save()
+	}
+}
+
+// bulkIDs returns the IDs of the tasks picked by a bulk command from it's only argument (the selection) and the
+// filter flags
+func bulkIDs(a *app, args []string, filter func(a *app) (todo.Filter, error)) ([]string, error) {
+	if len(args) > 1 {
+		return nil, usageErrorf("unexpected arguments %v. Separate IDs and positions with commas (e.g: 1-5,8)", args[1:])
+	}
+	f, err := filter(a)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := ""
+	if len(args) == 1 {
+		sel = args[0]
+	}
+	return selectIDs(a, sel, f)
+}
+
+// reopenCmd marks a completed task as active again
+func reopenCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if err := requireArgs(args, 1, "the ID or position of the task"); err != nil {
+			return err
+		}
+		pos, err := a.list.Resolve(args[0])
+		if err != nil {
+			return err
+		}
+		// call Reopen() to clear the Done and CompletedAt fields, keeping the previous completion
+		if err := a.list.Reopen(pos); err != nil {
+			return err
+		}
+		return a.save()
+	}
+}
+
+// rmCmd deletes the selected tasks
+func rmCmd(fs *flag.FlagSet) runFunc {
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		ids, err := bulkIDs(a, args, filter)
+		if err != nil {
+			return err
+		}
+		// calls DeleteAll() to remove every task at once
+		if err := a.list.DeleteAll(ids); err != nil {
+			return err
+		}
+		// save the updated list on disk.
+		return a.save()
+	}
+}
+
+// tagCmd adds tags to (or removes them from) the selected tasks
+func tagCmd(fs *flag.FlagSet) runFunc {
+	remove := fs.Bool("remove", false, "Remove the tags instead of adding them")
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		f, err := filter(a)
+		if err != nil {
+			return err
+		}
+
+		// without filter flags, the first argument is the selection
+		sel := ""
+		if f.IsZero() && len(args) > 0 {
+			sel, args = args[0], args[1:]
+		}
+		if len(args) == 0 {
+			return usageErrorf("expected the tags")
+		}
+
+		ids, err := selectIDs(a, sel, f)
+		if err != nil {
+			return err
+		}
+		if *remove {
+			err = a.list.UntagAll(ids, args...)
+		} else {
+			err = a.list.TagAll(ids, args...)
+		}
+		if err != nil {
+			return err
+		}
+		return a.save()
+	}
+}
+
+// editCmd renames a task, changes it's priority, due date, recurrence, tags, dependencies or notes, or moves it under
+// another task. Only what is given is changed.
+func editCmd(fs *flag.FlagSet) runFunc {
+	priority := fs.String("priority", "", "New priority of the task: A-Z, 1-26, high/medium/low or none")
+	due := fs.String("due", "", "New due date of the task: YYYY-MM-DD, today, tomorrow, +3d, +2w or none")
+	every := fs.String("every", "", "New recurrence of the task: daily, weekdays, weekly[:mon,thu], every:3d, monthly[:15] or none")
+	parent := fs.String("parent", "", "Move the task under another one, given by it's ID or number, or none to make it a top-level task")
+	blockedBy := &stringList{}
+	fs.Var(blockedBy, "blocked-by", "ID or number of a task that has to be done before this one. Can be repeated")
+	unblock := &stringList{}
+	fs.Var(unblock, "unblock", "ID or number of a task this one no longer depends on. Can be repeated")
+	notes := fs.String("notes", "", "New notes of the task, or none to remove them. Use - to read them from STDIN")
+	tags := &stringList{}
+	fs.Var(tags, "tag", "Add a tag to the task. Can be repeated")
+	untags := &stringList{}
+	fs.Var(untags, "untag", "Remove a tag from the task. Can be repeated")
+	useEditor := fs.Bool("e", false, "Open the task in $VISUAL or $EDITOR to edit it's text and tags")
+
+	return func(a *app, args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("expected the ID or position of the task")
+		}
+		pos, err := a.list.Resolve(args[0])
+		if err != nil {
+			return err
+		}
+
+		c := todo.Changes{AddTags: *tags, RemoveTags: *untags}
+
+		// the new text comes from the remaining arguments or from the editor
+		text := ""
+		switch {
+		case len(args) > 1 && *useEditor:
+			return usageErrorf("give the new text or -e, not both")
+		case len(args) > 1:
+			text = strings.Join(args[1:], " ")
+		case *useEditor:
+			current := (*a.list)[pos-1]
+			text, err = editText(a, strings.Join(append([]string{current.Task}, current.Tags...), " "))
+			if err != nil {
+				return err
+			}
+			// the tags were shown in the editor, so the ones left in the text replace them
+			c.RemoveTags = append(c.RemoveTags, current.Tags...)
+		}
+		if text != "" {
+			task, textTags := todo.ParseTags(text)
+			c.Task = &task
+			c.AddTags = append(textTags, c.AddTags...)
+		}
+
+		if *priority != "" {
+			c.Priority = priority
+		}
+		if *due != "" {
+			d, err := todo.ParseDate(*due, a.now)
+			if err != nil {
+				return usageError{err: err}
+			}
+			c.Due = &d
+		}
+		if *every != "" {
+			if _, err := todo.ParseRecurrence(*every); err != nil {
+				return usageError{err: err}
+			}
+			c.Recur = every
+		}
+		switch *notes {
+		case "":
+		case "none":
+			c.Notes = new(string)
+		case "-":
+			data, err := io.ReadAll(a.stdin)
+			if err != nil {
+				return err
+			}
+			text := string(data)
+			c.Notes = &text
+		default:
+			c.Notes = notes
+		}
+
+		if err := a.list.Edit(pos, c); err != nil {
+			return err
+		}
+		// then move it under it's new parent
+		if *parent != "" {
+			ppos := 0
+			if *parent != "none" {
+				if ppos, err = a.list.Resolve(*parent); err != nil {
+					return err
+				}
+			}
+			if err := a.list.SetParent(pos, ppos); err != nil {
+				return err
+			}
+		}
+		// and change it's dependencies
+		unblocks, err := resolveAll(a.list, *unblock)
+		if err != nil {
+			return err
+		}
+		for _, b := range unblocks {
+			if err := a.list.Unblock(pos, b); err != nil {
+				return err
+			}
+		}
+		blockers, err := resolveAll(a.list, *blockedBy)
+		if err != nil {
+			return err
+		}
+		for _, b := range blockers {
+			if err := a.list.Block(pos, b); err != nil {
+				return err
+			}
+		}
+		return a.save()
+	}
+}
+
+// archiveCmd moves the selected completed tasks (all of them by default) to the archive
+func archiveCmd(fs *flag.FlagSet) runFunc {
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		if len(args) > 1 {
+			return usageErrorf("unexpected arguments %v. Separate IDs and positions with commas (e.g: 1-5,8)", args[1:])
+		}
+		f, err := filter(a)
+		if err != nil {
+			return err
+		}
+		// only completed tasks are archived
+		if f.Status == todo.StatusActive {
+			return usageErrorf("only completed tasks can be archived")
+		}
+		f.Status = todo.StatusDone
+
+		sel := ""
+		if len(args) == 1 {
+			sel = args[0]
+		}
+		ids, err := selectIDs(a, sel, f)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			fmt.Fprintln(a.stdout, "Archived 0 task(s)")
+			return nil
+		}
+
+		archive, archiveStore, err := a.openArchive()
+		if err != nil {
+			return err
+		}
+		// subtasks are archived along with their parent, so count what left the list
+		before := len(*a.list)
+		archiveBefore := archive.Clone()
+		if err := a.list.Archive(archive, ids); err != nil {
+			return err
+		}
+
+		// save the archive first. If the list fails to save, the tasks are in both files rather than lost
+		if err := archiveStore.Save(archive); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Archived %d task(s)\n", before-len(*a.list))
+		return a.saveArchive(archiveBefore, *archive)
+	}
+}
+
+// archivedCmd prints the archived tasks, with the same filters as the list command. The positions displayed are
+// the positions on the archive, used by the restore command.
+func archivedCmd(fs *flag.FlagSet) runFunc {
+	verbose := fs.Bool("verbose", false, "Display verbose output")
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		f, err := filter(a)
+		if err != nil {
+			return err
+		}
+		archive, _, err := a.openArchive()
+		if err != nil {
+			return err
+		}
+
+		view := archive.Filter(f)
+		a.listed(view)
+		if *verbose {
+			fmt.Fprint(a.stdout, view.Verbose(a.now))
+			return nil
+		}
+		fmt.Fprint(a.stdout, view)
+		return nil
+	}
+}
+
+// restoreCmd moves the selected archived tasks back to the end of the list. With -backup it rolls the list back to a
+// backup instead. It loads the list itself, as a list too damaged to load can still be rolled back.
+func restoreCmd(fs *flag.FlagSet) runFunc {
+	backup := fs.String("backup", "", "Roll the list back to this backup (see todo backups) instead of restoring archived tasks")
+
+	return func(a *app, args []string) error {
+		if *backup != "" {
+			if len(args) > 0 {
+				return usageErrorf("unexpected arguments %v", args)
+			}
+			return restoreBackup(a, *backup)
+		}
+		if err := requireArgs(args, 1, "the IDs or positions of the archived tasks (e.g: 1-3,5)"); err != nil {
+			return err
+		}
+
+		// the list is loaded here, as restoring a backup doesn't load it
+		if err := a.open(); err != nil {
+			return err
+		}
+		defer a.close()
+		archive, archiveStore, err := a.openArchive()
+		if err != nil {
+			return err
+		}
+		ids, err := archive.Select(args[0])
+		if err != nil {
+			return err
+		}
+		archiveBefore := archive.Clone()
+		if err := a.list.Restore(archive, ids); err != nil {
+			return err
+		}
+
+		// save the list first. If the archive fails to save, the tasks are in both files rather than lost
+		if err := a.saveArchive(archiveBefore, *archive); err != nil {
+			return err
+		}
+		return archiveStore.Save(archive)
+	}
+}
+
+// undoCmd reverts the last change recorded in the history. Tasks archived or restored by it are moved back
+func undoCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		step, err := a.history.Undo(a.list)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Undone %s:\n%s\n", step.Name, indent(step.String()))
+		return a.commitStep(step, step.RevertArchive)
+	}
+}
+
+// redoCmd applies again the last undone change
+func redoCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		step, err := a.history.Redo(a.list)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Redone %s:\n%s\n", step.Name, indent(step.String()))
+		return a.commitStep(step, step.ApplyArchive)
+	}
+}
+
+// historyCmd lists the recorded changes, the most recent first. Undone changes that can be redone are marked
+func historyCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+
+		a.listedHistory()
+
+		// the undone steps come first, the first one to be redone closest to the top of the undo steps
+		for _, step := range a.history.Undone {
+			fmt.Fprintf(a.stdout, "(undone) %s %s\n%s\n", step.At.Format(time.DateTime), step.Name, indent(step.String()))
+		}
+		for i := len(a.history.Steps) - 1; i >= 0; i-- {
+			step := a.history.Steps[i]
+			fmt.Fprintf(a.stdout, "%d: %s %s\n%s\n", i+1, step.At.Format(time.DateTime), step.Name, indent(step.String()))
+		}
+		return nil
+	}
+}
+
+// indent adds 4 spaces before every line of s
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+// helpCmd prints the help of the tool, or of the given command
+func helpCmd(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) == 0 {
+			global, _ := a.globalFlags(a.stdout)
+			global.Usage()
+			return nil
+		}
+
+		cmd, ok := lookupCommand(args[0])
+		if !ok {
+			return usageErrorf("unknown command %q", args[0])
+		}
+		cmdFlags := a.commandFlags(cmd, a.stdout)
+		cmd.setup(cmdFlags)
+		cmdFlags.Usage()
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dupakarovsky/todo"
+)
+
+//=================================
+// DOCTOR
+//=================================
+
+// salvager is implemented by the stores that can read what's left of a damaged list (see todo.JSONStore.Salvage)
+type salvager interface {
+	Salvage(l *todo.List) ([]todo.Problem, error)
+}
+
+// doctorCmd checks the list for problems and repairs it, keeping a copy of the original file. It loads the list
+// itself, as a damaged list can't be loaded the usual way. Only the stores that can salvage a damaged list are
+// supported, as the others can neither read nor keep a copy of it.
+func doctorCmd(fs *flag.FlagSet) runFunc {
+	dryRun := fs.Bool("n", false, "Only report the problems, without repairing the list")
+
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		store, err := todo.OpenStore(a.storeName, a.filename)
+		if err != nil {
+			return usageError{err: err}
+		}
+		s, ok := store.(salvager)
+		if !ok {
+			return usageErrorf("doctor doesn't support the %q store. Use the %q store", a.storeName, todo.DefaultStore)
+		}
+		lock, err := todo.Lock(a.filename)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+
+		// read the list, salvaging what's left of it
+		l := todo.List{}
+		problems, err := s.Salvage(&l)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, l.Validate()...)
+		a.foundProblems(problems)
+		a.listed(l.View())
+
+		if len(problems) == 0 {
+			fmt.Fprintln(a.stdout, "No problems found")
+			return nil
+		}
+		for _, p := range problems {
+			fmt.Fprintln(a.stdout, p)
+		}
+		if *dryRun {
+			return fmt.Errorf("found %d problem(s). Run 'todo doctor' without -n to repair the list", len(problems))
+		}
+
+		// keep a copy of the file as it was before writing the repaired list over it
+		backup, err := keepCopy(a.filename, a.now)
+		if err != nil {
+			return err
+		}
+		l.Repair(a.now)
+		if err := store.Save(&l); err != nil {
+			return err
+		}
+		a.listed(l.View())
+		fmt.Fprintf(a.stdout, "Repaired %d problem(s). The original file was kept in %s\n", len(problems), backup)
+		return nil
+	}
+}
+
+// keepCopy copies filename to filename.<date>.bak and returns the name of the copy. Copies made in the same second
+// get a number after the date, so none is overwritten.
+func keepCopy(filename string, now time.Time) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	base := fmt.Sprintf("%s.%s", filename, now.Format("20060102-150405"))
+	name := base + ".bak"
+	for n := 1; ; n++ {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, os.ErrExist) {
+			name = fmt.Sprintf("%s-%d.bak", base, n)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		return name, f.Close()
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultEditor is used when neither VISUAL nor EDITOR are set, or they only hold blanks
+const defaultEditor = "vi"
+
+// editText will open text in the user's editor and return the first non blank line once the editor exits.
+// The editor is taken from VISUAL or EDITOR and may include arguments (eg.: "code -w").
+func editText(a *app, text string) (string, error) {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	// write the text to a temp file for the editor to open
+	temp, err := os.CreateTemp("", "todo-edit-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.WriteString(text + "\n"); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+
+	// run the editor connected to the terminal. a.stdout is discarded with -json, so the editor writes to os.Stdout
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], temp.Name())...)
+	cmd.Stdin = a.stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = a.stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor %q: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(temp.Name())
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(edited), "\n") {
+		if strings.TrimSpace(line) != "" {
+			return strings.TrimSpace(line), nil
+		}
+	}
+	return "", fmt.Errorf("Task cannot be blank")
+}
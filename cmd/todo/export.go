@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dupakarovsky/todo"
+)
+
+//=================================
+// IMPORT AND EXPORT
+//=================================
+
+// exportFormats maps the name of each format the export command writes to the method writing a view in it
+var exportFormats = map[string]func(v todo.View, w io.Writer) error{
+	"todotxt":  todo.View.WriteTodoTxt,
+	"csv":      todo.View.WriteCSV,
+	"markdown": todo.View.WriteMarkdown,
+	"md":       todo.View.WriteMarkdown,
+}
+
+// formatNames returns the names of the export formats, sorted
+func formatNames() []string {
+	names := []string{}
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// exportCmd writes the tasks (or the ones matching the filter flags) to STDOUT or a file in another format
+func exportCmd(fs *flag.FlagSet) runFunc {
+	format := fs.String("format", "todotxt", fmt.Sprintf("Format to export the tasks to %v", formatNames()))
+	output := fs.String("o", "", "Write to this file instead of STDOUT")
+	filter := filterFlags(fs)
+
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		write, ok := exportFormats[strings.ToLower(*format)]
+		if !ok {
+			return usageErrorf("invalid format %q. Use one of %v", *format, formatNames())
+		}
+		f, err := filter(a)
+		if err != nil {
+			return err
+		}
+
+		view := a.list.Filter(f)
+		if *output == "" {
+			return write(view, a.stdout)
+		}
+
+		buf := bytes.Buffer{}
+		if err := write(view, &buf); err != nil {
+			return err
+		}
+		return os.WriteFile(*output, buf.Bytes(), 0644)
+	}
+}
+
+// importCmd reads tasks in the todo.txt format from a file or STDIN. Tasks exported with their id: are updated
+// instead of added again.
+func importCmd(fs *flag.FlagSet) runFunc {
+	format := fs.String("format", "todotxt", "Format of the tasks to import. Accepts: todotxt")
+
+	return func(a *app, args []string) error {
+		if len(args) > 1 {
+			return usageErrorf("unexpected arguments %v", args[1:])
+		}
+		if strings.ToLower(*format) != "todotxt" {
+			return usageErrorf("invalid format %q. Use todotxt", *format)
+		}
+
+		// read from the file, or STDIN when there's none or it's -
+		var r io.Reader = a.stdin
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		added, updated, err := a.list.ImportTodoTxt(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Imported %d new task(s), updated %d\n", added, updated)
+		return a.save()
+	}
+}
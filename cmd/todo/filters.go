@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/dupakarovsky/todo"
+)
+
+// filterFlags registers the flags that build a todo.Filter on the FlagSet. They're shared by the list command and
+// the bulk commands (done, rm, tag). The returned function builds the Filter once the flags are parsed.
+// INFO: dates accept YYYY-MM-DD, yesterday, today, -7d, etc.
+func filterFlags(fs *flag.FlagSet) func(a *app) (todo.Filter, error) {
+	active := fs.Bool("active", false, "Only tasks that are active")
+	done := fs.Bool("done", false, "Only tasks that are completed")
+	match := fs.String("match", "", "Only tasks containing the text, ignoring case")
+	priority := fs.String("priority", "", "Only tasks with the priorities (e.g: A, A-C, B,none)")
+	createdAfter := fs.String("created-after", "", "Only tasks created on or after the date")
+	createdBefore := fs.String("created-before", "", "Only tasks created before the date")
+	olderThan := fs.String("older-than", "", "Only tasks created longer than the span ago (e.g: 30d)")
+	completedAfter := fs.String("completed-after", "", "Only tasks completed on or after the date")
+	completedBefore := fs.String("completed-before", "", "Only tasks completed before the date")
+	tags := &stringList{}
+	fs.Var(tags, "tag", "Only tasks with the tag. Can be repeated to require several tags (e.g: -tag +project)")
+
+	return func(a *app) (todo.Filter, error) {
+		f := todo.Filter{Text: *match, Tags: *tags}
+		switch {
+		case *active && *done:
+			return f, usageErrorf("-active and -done can't be used together")
+		case *active:
+			f.Status = todo.StatusActive
+		case *done:
+			f.Status = todo.StatusDone
+		}
+
+		if *priority != "" {
+			p, err := todo.ParsePriorities(*priority)
+			if err != nil {
+				return f, usageError{err: err}
+			}
+			f.Priorities = p
+		}
+
+		dates := []struct {
+			value string
+			field *time.Time
+		}{
+			{*createdAfter, &f.CreatedAfter},
+			{*createdBefore, &f.CreatedBefore},
+			{*completedAfter, &f.CompletedAfter},
+			{*completedBefore, &f.CompletedBefore},
+		}
+		for _, d := range dates {
+			t, err := todo.ParseDate(d.value, a.now)
+			if err != nil {
+				return f, usageError{err: err}
+			}
+			*d.field = t
+		}
+
+		// -older-than is a shortcut for -created-before, relative to now
+		if *olderThan != "" {
+			span, err := todo.ParseSpan(*olderThan)
+			if err != nil {
+				return f, usageError{err: err}
+			}
+			before := a.now.Add(-span)
+			if f.CreatedBefore.IsZero() || before.Before(f.CreatedBefore) {
+				f.CreatedBefore = before
+			}
+		}
+
+		return f, nil
+	}
+}
+
+// selectIDs returns the IDs of the tasks picked by a bulk command: the tasks in the selection (eg.: 1-5,8) that
+// match the filter. Either can be left out, but not both, so a command never acts on the whole list by accident.
+func selectIDs(a *app, sel string, f todo.Filter) ([]string, error) {
+	if sel == "" && f.IsZero() {
+		return nil, usageErrorf("expected the tasks to act on: IDs, positions or ranges (e.g: 1-5,8) or filter flags")
+	}
+
+	// the tasks matching the filter
+	matching := a.list.Filter(f).IDs()
+	if sel == "" {
+		return matching, nil
+	}
+
+	// the selected tasks that match the filter
+	selected, err := a.list.Select(sel)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, id := range selected {
+		for _, m := range matching {
+			if id == m {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
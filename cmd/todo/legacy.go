@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+)
+
+//=================================
+// DEPRECATED FLAGS
+//=================================
+// Before the subcommands, the tool was driven by flags (eg.: ./todo -add task, ./todo -complete 1). They're kept
+// working as aliases: the flags are translated into the matching command and it's arguments.
+
+// legacy holds the values of the deprecated flags
+type legacy struct {
+	add        *bool
+	list       *bool
+	complete   *string
+	del        *string
+	verbose    *bool
+	active     *bool
+	prioritize *string
+	priority   *string
+	due        *string
+	overdue    *bool
+	dueWithin  *string
+	sortBy     *string
+}
+
+// legacyFlags registers the deprecated flags on the global FlagSet
+func legacyFlags(fs *flag.FlagSet) *legacy {
+	return &legacy{
+		add:        fs.Bool("add", false, "Deprecated: use 'todo add'"),
+		list:       fs.Bool("list", false, "Deprecated: use 'todo list'"),
+		complete:   fs.String("complete", "", "Deprecated: use 'todo done'"),
+		del:        fs.String("del", "", "Deprecated: use 'todo rm'"),
+		verbose:    fs.Bool("verbose", false, "Deprecated: use 'todo list -verbose'"),
+		active:     fs.Bool("active", false, "Deprecated: use 'todo list -active'"),
+		prioritize: fs.String("prioritize", "", "Deprecated: use 'todo edit -priority'"),
+		priority:   fs.String("priority", "", "Deprecated: use the -priority flag of 'todo add' or 'todo edit'"),
+		due:        fs.String("due", "", "Deprecated: use the -due flag of 'todo add'"),
+		overdue:    fs.Bool("overdue", false, "Deprecated: use 'todo list -overdue'"),
+		dueWithin:  fs.String("due-within", "", "Deprecated: use 'todo list -due-within'"),
+		sortBy:     fs.String("sort", "", "Deprecated: use 'todo list -sort'"),
+	}
+}
+
+// command translates the deprecated flags into a command name and it's arguments. When none of them is set, the
+// first of the remaining arguments is the command. The listing flags are combined into a single list command;
+// otherwise the first mutating flag, in the order the tool used to check them, wins.
+func (lg *legacy) command(args []string) (string, []string, error) {
+	// the listing flags can be combined
+	listArgs := []string{}
+	if *lg.active {
+		listArgs = append(listArgs, "-active")
+	}
+	if *lg.verbose {
+		listArgs = append(listArgs, "-verbose")
+	}
+	if *lg.overdue {
+		listArgs = append(listArgs, "-overdue")
+	}
+	if *lg.dueWithin != "" {
+		listArgs = append(listArgs, "-due-within", *lg.dueWithin)
+	}
+	if *lg.sortBy != "" {
+		listArgs = append(listArgs, "-sort", *lg.sortBy)
+	}
+
+	switch {
+	case *lg.list || len(listArgs) > 0:
+		return "list", listArgs, nil
+
+	case *lg.complete != "":
+		return "done", []string{*lg.complete}, nil
+
+	case *lg.add:
+		addArgs := []string{}
+		if *lg.priority != "" {
+			addArgs = append(addArgs, "-priority", *lg.priority)
+		}
+		if *lg.due != "" {
+			addArgs = append(addArgs, "-due", *lg.due)
+		}
+		// the non flag arguments are the task. "--" keeps a task starting with "-" from being read as a flag
+		return "add", append(append(addArgs, "--"), args...), nil
+
+	case *lg.prioritize != "":
+		// an empty -priority used to clear the priority. edit clears it with "none"
+		p := *lg.priority
+		if p == "" {
+			p = "none"
+		}
+		return "edit", []string{"-priority", p, *lg.prioritize}, nil
+
+	case *lg.del != "":
+		return "rm", []string{*lg.del}, nil
+
+	case *lg.priority != "" || *lg.due != "":
+		return "", nil, usageErrorf("the -priority and -due flags must follow a command. eg.: todo add -priority A task")
+	}
+
+	// no deprecated flag. The first argument is the command
+	if len(args) == 0 {
+		return "", nil, nil
+	}
+	return args[0], args[1:], nil
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -24,159 +26,374 @@ import (
 // name of the json file that'll be created.
 var todoFileName = "todo.json"
 
+// exit codes returned by the tool
+const (
+	exitOK    = 0 // the command succeeded
+	exitError = 1 // the command failed. eg.: the item doesn't exist or the file can't be saved
+	exitUsage = 2 // the command was called the wrong way. eg.: unknown command or flag, missing argument
+)
+
+// usageError marks an error caused by calling a command the wrong way. It makes the tool exit with exitUsage.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// usageErrorf formats a new usageError
+func usageErrorf(format string, a ...any) error {
+	return usageError{err: fmt.Errorf(format, a...)}
+}
+
 // getTask will accept a first parameter that implements the io.Reader interface. Then a variadict string parameter to collect all
 // others arguments passd in into a slice.
-func getTask(r io.Reader, args ...string) (string, error) {
+// When reading from r, the first line is the task and the lines after it are it's notes.
+func getTask(r io.Reader, args ...string) (string, string, error) {
 
 	// checks whether we passed any arguments
 	if len(args) > 0 {
 		// arguments are provided. concatenate them into a string separeted by space and return it
-		return strings.Join(args, " "), nil
+		return strings.Join(args, " "), "", nil
 	}
 
 	// no arguments are provided. Start scanning the stdin input
 	// instantiate a new Scanner to read data from the r (stdin)
 	scanner := bufio.NewScanner(r)
 
-	// scan a single line
+	// scan the first line, holding the task
 	scanner.Scan()
 	if err := scanner.Err(); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// check the length for the scanned line
 	if len(scanner.Text()) == 0 {
-		return "", fmt.Errorf("Task cannot be blank")
+		return "", "", fmt.Errorf("Task cannot be blank")
+	}
+	task := scanner.Text()
+
+	// scan the rest of the lines, holding the notes
+	notes := []string{}
+	for scanner.Scan() {
+		notes = append(notes, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
 	}
-	// return the string of the scanned line
-	return scanner.Text(), nil
+
+	// return the string of the first line and the notes
+	return task, strings.Join(notes, "\n"), nil
 }
 
-func main() {
+// app holds everything a command needs to run: the list, where it's stored and where to read and write
+type app struct {
+	filename  string
+	storeName string
+	backups   string // the backup policy of the list file. See todo.ParseBackupPolicy
+	store     todo.Store
+	lock      *todo.FileLock
+	list      *todo.List
+	before    todo.List     // the list as it was loaded, to record the changes in the history
+	history   *todo.History // the steps that can be undone and redone
+	command   string        // name of the command being run
+	json      bool          // print the result of the command as JSON
+	result    *output       // the JSON document of the command, when in -json mode
+	now       time.Time
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+}
 
-	// the output below will be displayed when the ./todo -h is invoked.
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "%s tool. Developed by Dupakarovksy\n", os.Args[0])
-		fmt.Fprintf(flag.CommandLine.Output(), "Copyright 2024\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage Information:\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "To add a new task use the -add flag followed by the task's name:\n(e.g: ./todo -add My New Task)\n\n")
-		flag.PrintDefaults()
+// openStore will open the storage backend of the list file, with the backup policy set
+func (a *app) openStore() (todo.Store, error) {
+	store, err := todo.OpenStore(a.storeName, a.filename)
+	if err != nil {
+		return nil, usageError{err: err}
 	}
 
-	// Add a list of flags to be passed to the command line
-	add := flag.Bool("add", false, "Add task to the ToDo list")
-	list := flag.Bool("list", false, "List all ToDo items")
-	complete := flag.Int("complete", 0, "Mark item as completed")
-	// INFO: add falgs: -del, -verbose, -active
-	del := flag.Int("del", 0, "Delete a task from the ToDo list")
-	verbose := flag.Bool("verbose", false, "Display verbose output")
-	active := flag.Bool("active", false, "Display active tasks only")
+	// keep backups of the list file before each save. Only the json store keeps them
+	if js, ok := store.(*todo.JSONStore); ok {
+		if js.BackupPolicy, err = todo.ParseBackupPolicy(a.backups); err != nil {
+			return nil, usageError{err: err}
+		}
+	}
+	return store, nil
+}
 
-	flag.Parse()
+// open will open the storage backend, take the lock over the file and load the list
+func (a *app) open() error {
+	// open the storage backend for the file
+	store, err := a.openStore()
+	if err != nil {
+		return err
+	}
+	a.store = store
 
-	// check whether we have a environmental variable set. If so, set it as the value for the todoFileName var.
-	if os.Getenv("TODO_FILENAME") != "" {
-		todoFileName = os.Getenv("TODO_FILENAME")
-	}
-
-	// define a instance of a Todo List initialize in it's zero value
-	l := &todo.List{}
-
-	// try to read the todoFileName using the Get() method.
-	if err := l.Get(todoFileName); err != nil {
-		// if fails, print the error to the Standard Error in Terminal
-		fmt.Fprintln(os.Stderr, err)
-		// exit the process with code 1 (error condition)
-		os.Exit(1)
-	}
-
-	// File doesn't exist or file was successfuly read:
-	// check if any arguments were passed to the command line
-	switch {
-
-	// INFO: check case where the '-active' flag is passed
-	case *active:
-		output := ""
-		// add a prefix to be displayed
-		for idx, item := range *l {
-			// display only active tasks
-			if !item.Done {
-				prefix := "[ ] "
-				output += fmt.Sprintf("%s%d: %s\n", prefix, idx+1, item.Task)
-			}
-		}
-		fmt.Fprint(os.Stdout, output)
-
-	// INFO: check case where the '-verbose' flag is passed
-	case *verbose:
-		status := "Active"
-		output := ""
-		prefix := "[ ] "
-		for idx, item := range *l {
-			timeString := item.CreatedAt.Format(time.UnixDate)
-			if item.Done {
-				status = "Done"
-				prefix = "[x] "
-			}
-			output += fmt.Sprintf("%s%d: %s | Created: %s | Status: %s\n", prefix, idx+1, item.Task, timeString, status)
-		}
-		fmt.Fprint(os.Stdout, output)
-
-	// check for the case where the '-list' flag is passed
-	case *list:
-		// Print(l) uses the default String() for the type, which in our case uses a for-range
-		fmt.Print(l)
-
-		// check for the case where the '-complete' flag is passed with positive value
-	case *complete > 0:
-		// call the Complete() to update Done and CompletedAt fields
-		if err := l.Complete(*complete); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	// take the lock over the file before reading it, so parallel runs can't interleave their Get -> Save cycles
+	// and lose each others changes. The lock is released by close() or when the process exits.
+	lock, err := todo.Lock(a.filename)
+	if err != nil {
+		return err
+	}
+	a.lock = lock
+
+	// define a instance of a Todo List initialize in it's zero value and read the file into it
+	a.list = &todo.List{}
+	if err := a.store.Load(a.list); err != nil {
+		// point to the doctor when the file is damaged and the doctor can repair it
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if _, ok := a.store.(salvager); ok && (errors.As(err, &syntaxErr) || errors.As(err, &typeErr)) {
+			return fmt.Errorf("%w. Run 'todo doctor' to repair the list", err)
 		}
-		// save the updated list on disk.
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+		return err
+	}
+	a.before = a.list.Clone()
+
+	// load the history of changes kept alongside the list file
+	a.history, err = todo.LoadHistory(a.historyFile())
+	return err
+}
+
+// historyFile returns the name of the file holding the history of changes
+func (a *app) historyFile() string {
+	return a.filename + ".history"
+}
+
+// close will release the lock taken by open
+func (a *app) close() {
+	if a.lock != nil {
+		a.lock.Unlock()
+	}
+}
+
+// archiveFile returns the name of the file holding the archived tasks
+func (a *app) archiveFile() string {
+	return a.filename + ".archive"
+}
+
+// openArchive will load the archived tasks from the same kind of store as the list. It's called with the lock
+// over the list file held, which covers the archive as well.
+func (a *app) openArchive() (*todo.List, todo.Store, error) {
+	store, err := todo.OpenStore(a.storeName, a.archiveFile())
+	if err != nil {
+		return nil, nil, usageError{err: err}
+	}
+
+	archive := &todo.List{}
+	if err := store.Load(archive); err != nil {
+		return nil, nil, err
+	}
+	return archive, store, nil
+}
+
+// save will record the changes made by the command in the history and write the list back to the store
+func (a *app) save() error {
+	a.history.Record(a.command, a.before, *a.list)
+	return a.commit()
+}
+
+// saveArchive will record the changes made by the command to the list and the archive as a single step in the
+// history, and write the list and the history. Writing the archive is left to the command, which knows which of the
+// two files has to be written first.
+func (a *app) saveArchive(archiveBefore, archive todo.List) error {
+	a.history.RecordArchive(a.command, a.before, *a.list, archiveBefore, archive)
+	return a.commit()
+}
+
+// commitStep will write the list and the history after undoing or redoing a step. Steps that moved tasks to or from
+// the archive get their changes to the archive made by change as well. The file the tasks move to is written first,
+// so a failure leaves them in both files rather than lost.
+func (a *app) commitStep(step todo.Step, change func(archive *todo.List)) error {
+	if len(step.ArchiveChanges) == 0 {
+		return a.commit()
+	}
+
+	archive, archiveStore, err := a.openArchive()
+	if err != nil {
+		return err
+	}
+	change(archive)
+
+	if len(*a.list) >= len(a.before) {
+		if err := a.commit(); err != nil {
+			return err
 		}
+		return archiveStore.Save(archive)
+	}
+	if err := archiveStore.Save(archive); err != nil {
+		return err
+	}
+	return a.commit()
+}
+
+// commit will write the list and the history to disk, without recording anything
+func (a *app) commit() error {
+	if err := a.store.Save(a.list); err != nil {
+		return err
+	}
+	return a.history.Save(a.historyFile())
+}
+
+func main() {
+	a := &app{
+		filename: todoFileName,
+		now:      time.Now(),
+		stdin:    os.Stdin,
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+	}
 
-		// check for the case where the '-add' flag is passed
-	case *add:
-		// if true, well call the getTask() method with the os.Stdin (which implements io.Reader).
-		// for the variadic paramenter, pass the flag.Args() wich collects all non flag arguments passed to the command line.
-		t, err := getTask(os.Stdin, flag.Args()...)
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			os.Exit(1)
+	// check whether we have a environmental variable set. If so, set it as the value for the filename.
+	if os.Getenv("TODO_FILENAME") != "" {
+		a.filename = os.Getenv("TODO_FILENAME")
+	}
+
+	os.Exit(a.run(os.Args[1:]))
+}
+
+// globalFlags creates the FlagSet of the flags given before the command. Errors and the help are written to w.
+func (a *app) globalFlags(w io.Writer) (*flag.FlagSet, *legacy) {
+	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { a.usage(fs) }
+
+	// the storage backend can be selected with the -store flag or the TODO_STORE env var
+	fs.StringVar(&a.storeName, "store", os.Getenv("TODO_STORE"), fmt.Sprintf("Storage backend to use %v (default %q)", todo.StoreNames(), todo.DefaultStore))
+	// so can the backup policy, with -backups or the TODO_BACKUPS env var
+	backups := os.Getenv("TODO_BACKUPS")
+	if backups == "" {
+		backups = defaultBackups
+	}
+	fs.StringVar(&a.backups, "backups", backups, "Backups of the list kept before each save: a number, daily[:N] or off")
+	fs.BoolVar(&a.json, "json", false, "Print the result of the command as a JSON document. Can be given after the command as well")
+
+	// the flags the tool used before the subcommands were introduced. They're kept working as aliases
+	return fs, legacyFlags(fs)
+}
+
+// run will parse the global flags and run the selected command, returning the exit code
+func (a *app) run(args []string) int {
+	fs, legacy := a.globalFlags(a.stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
-		// call Add() with the string getTasks returns
-		l.Add(t)
+		return exitUsage
+	}
 
-		// save the updated list on disk.
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprint(os.Stderr, err)
-			os.Exit(1)
+	// translate the deprecated flags into the matching command
+	name, cmdArgs, err := legacy.command(fs.Args())
+	if err != nil {
+		fmt.Fprintln(a.stderr, err)
+		return exitUsage
+	}
+	if name == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	return a.runCommand(name, cmdArgs)
+}
+
+// runCommand will parse the command's flags, load the list and run it, returning the exit code
+func (a *app) runCommand(name string, args []string) int {
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Fprintf(a.stderr, "unknown command %q. Run 'todo help' for usage.\n", name)
+		return exitUsage
+	}
+
+	a.command = cmd.name
+	fs := a.commandFlags(cmd, a.stderr)
+	runFunc := cmd.setup(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
+		return exitUsage
+	}
+
+	// in -json mode the text output of the command is discarded. The JSON document is printed once it's done.
+	stdout := a.stdout
+	if a.json && cmd.jsonOutput() {
+		a.result = &output{Version: outputVersion, Command: cmd.name}
+		a.stdout = io.Discard
+	}
 
-		//INFO: check case where the '-del' flag is passed with a positive value
-	case *del > 0:
-		// calls the Delete() method with the pos of the -del value
-		if err := l.Delete(*del); err != nil {
-			fmt.Fprint(os.Stderr, err)
-			os.Exit(1)
+	// commands like help don't need the list
+	if !cmd.noList {
+		if err := a.open(); err != nil {
+			a.stdout = stdout
+			return a.fail(err)
 		}
+		defer a.close()
+	}
 
-		//INFO: save the updated on disk
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprint(os.Stderr, err)
-			os.Exit(1)
+	err := runFunc(a, fs.Args())
+	a.stdout = stdout
+	if err != nil {
+		return a.fail(err)
+	}
+	if a.result != nil {
+		if err := a.writeResult(a.stdout); err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return exitError
 		}
+	}
+	return exitOK
+}
+
+// commandFlags creates the FlagSet of the command, adding the -json flag to the commands that support it
+func (a *app) commandFlags(cmd command, w io.Writer) *flag.FlagSet {
+	fs := cmd.flagSet(w)
+	if cmd.jsonOutput() {
+		fs.BoolVar(&a.json, "json", a.json, "Print the result as a JSON document instead of text")
+	}
+	return fs
+}
+
+// fail will print the error to the Standard Error and return the matching exit code
+// In -json mode the error is printed as a JSON document as well.
+func (a *app) fail(err error) int {
+	fmt.Fprintln(a.stderr, err)
+
+	// nothing was saved, so no items changed
+	if a.result != nil {
+		a.result.Error = err.Error()
+		a.result.Items = []outputItem{}
+		a.result.listed = true
+		a.writeResult(a.stdout)
+	}
+
+	var uerr usageError
+	if errors.As(err, &uerr) {
+		return exitUsage
+	}
+	return exitError
+}
 
-		// update the default case to output an error to stderr
-	default:
-		// Check for error during save.
-		fmt.Fprintln(os.Stderr, "Invalid Option")
-		// exit the program if errors saving
-		os.Exit(1)
+// usage will print the help of the tool, listing the commands and the global flags
+func (a *app) usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "todo tool. Developed by Dupakarovksy\n")
+	fmt.Fprintf(out, "Copyright 2024\n")
+	fmt.Fprintf(out, "Usage Information:\n")
+	fmt.Fprintf(out, "  todo [-store name] [-backups policy] [-json] <command> [flags] [arguments]\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(out, "\nRun 'todo help <command>' or 'todo <command> -h' for the flags of a command.\n")
+	fmt.Fprintf(out, "To add a new task use the add command followed by the task's name:\n(e.g: ./todo add My New Task)\n")
+	fmt.Fprintf(out, "Use -priority and -due to give it a priority and a due date:\n(e.g: ./todo add -priority A -due tomorrow My Urgent Task)\n\n")
+	fmt.Fprintf(out, "The list file defaults to %s and can be changed with the TODO_FILENAME env var.\n", todoFileName)
+	fmt.Fprintf(out, "The storage backend can be set with -store or the TODO_STORE env var.\n")
+	fmt.Fprintf(out, "Backups of the list are kept in %s.backups before each save. Set how many with -backups or the\nTODO_BACKUPS env var: a number, daily[:N] or off (default %s). List them with 'todo backups'.\n", todoFileName, defaultBackups)
+	fmt.Fprintf(out, "With -json the commands print a JSON document (version %d) with the items they listed or changed.\n", outputVersion)
+	fmt.Fprintf(out, "Exit codes: %d success, %d failure, %d usage error.\n\n", exitOK, exitError, exitUsage)
+	fmt.Fprintf(out, "Global flags (the others are deprecated aliases of the commands):\n")
+	fs.PrintDefaults()
 }
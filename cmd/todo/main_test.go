@@ -1,13 +1,19 @@
 package main_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/dupakarovsky/todo"
 )
 
 //====================
@@ -63,6 +69,10 @@ func TestMain(m *testing.M) {
 	fmt.Println("Cleaning up..")
 	os.Remove(binName)
 	os.Remove(fileName)
+	os.Remove(fileName + ".lock")
+	os.Remove(fileName + ".history")
+	os.Remove(fileName + ".archive")
+	os.RemoveAll(fileName + ".backups")
 
 	// exit with the returned code
 	os.Exit(code)
@@ -156,6 +166,28 @@ func TestTodoCLI(t *testing.T) {
 		}
 	})
 
+	// Create a subtest (CompleteTaskByID); reads the saved file to find the ID of the 2nd task and completes it by ID
+	t.Run("CompleteTaskByID", func(t *testing.T) {
+		l := todo.List{}
+		if err := l.Get(fileName); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command(cmdPath, "-complete", l[1].ID)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		// reload the file. The 2nd task should be done now
+		l = todo.List{}
+		if err := l.Get(fileName); err != nil {
+			t.Fatal(err)
+		}
+		if !l[1].Done {
+			t.Errorf("expected task %q to be completed", l[1].Task)
+		}
+	})
+
 	//INFO: Create a subtest (DeleteTask);
 	t.Run("DeleteTask", func(t *testing.T) {
 
@@ -170,6 +202,935 @@ func TestTodoCLI(t *testing.T) {
 
 }
 
+// TestTodoCLIConcurrentAdd will run several '-add' commands at the same time against the same file. With the file
+// lock in place, every one of the tasks should make it into the list.
+func TestTodoCLIConcurrentAdd(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmdPath := filepath.Join(dir, binName)
+
+	// use a separate file so this test doesn't interfere with TestTodoCLI
+	file := filepath.Join(t.TempDir(), "concurrent.json")
+
+	const runs = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cmd := exec.Command(cmdPath, "-add", fmt.Sprintf("task %d", i))
+			cmd.Env = append(os.Environ(), "TODO_FILENAME="+file)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("%v: %s", err, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	l := todo.List{}
+	if err := l.Get(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != runs {
+		t.Errorf("expected %d tasks; got %d instead", runs, len(l))
+	}
+}
+
+// runCode is a helper that executes the compiled binary with args against the given list file and returns the
+// combined output and the exit code.
+func runCode(t *testing.T, file string, args ...string) (string, int) {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(filepath.Join(dir, binName), args...)
+	cmd.Env = append(os.Environ(), "TODO_FILENAME="+file)
+	out, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return string(out), 0
+	case errors.As(err, &exitErr):
+		return string(out), exitErr.ExitCode()
+	}
+	t.Fatal(err)
+	return "", 0
+}
+
+// runStdin runs the tool like run, writing input to it's STDIN
+func runStdin(t *testing.T, file, input string, args ...string) string {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(filepath.Join(dir, binName), args...)
+	cmd.Env = append(os.Environ(), "TODO_FILENAME="+file)
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("todo %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+// run is a helper like runCode. The test fails if the command exits with an error.
+func run(t *testing.T, file string, args ...string) string {
+	t.Helper()
+
+	out, code := runCode(t, file, args...)
+	if code != 0 {
+		t.Fatalf("%v: exit code %d: %s", args, code, out)
+	}
+	return out
+}
+
+// TestTodoCLIPriority will add tasks with priorities, change one of them and list them sorted by priority
+func TestTodoCLIPriority(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "-add", "no priority")
+	run(t, file, "-add", "-priority", "low", "low priority")
+	run(t, file, "-add", "-priority", "b", "medium priority")
+
+	// raise the priority of the 1st task to A
+	run(t, file, "-prioritize", "1", "-priority", "A")
+
+	out := run(t, file, "-list", "-sort", "priority")
+	expected := "[ ] 1: (A) no priority\n[ ] 3: (B) medium priority\n[ ] 2: (C) low priority\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+}
+
+// TestTodoCLIDue will add tasks with due dates and list the overdue and upcoming ones
+func TestTodoCLIDue(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "-add", "-due", "2000-01-01", "late task")
+	run(t, file, "-add", "-due", "+3d", "upcoming task")
+	run(t, file, "-add", "-due", "+30d", "later task")
+	run(t, file, "-add", "no due date")
+
+	out := run(t, file, "-overdue")
+	expected := "[ ] 1: late task\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	out = run(t, file, "-due-within", "1w")
+	expected = "[ ] 2: upcoming task\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the due date is shown in the verbose output
+	out = run(t, file, "-verbose", "-overdue")
+	if !strings.Contains(out, "Due: 2000-01-01 (overdue)") {
+		t.Errorf("expected the due date in %q", out)
+	}
+}
+
+// TestTodoCLISubcommands will run the subcommands, combine listing flags and check the exit codes
+func TestTodoCLISubcommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "task one")
+	run(t, file, "add", "-priority", "B", "task two")
+	run(t, file, "add", "task three")
+	run(t, file, "done", "1")
+	run(t, file, "rm", "3")
+	run(t, file, "edit", "-priority", "A", "-due", "2000-01-01", "2")
+
+	out := run(t, file, "list")
+	expected := "[x] 1: task one\n[ ] 2: (A) task two\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the listing flags can be combined
+	out = run(t, file, "ls", "-active", "-verbose")
+	if !strings.HasPrefix(out, "[ ] 2: (A) task two | ID: ") || strings.Contains(out, "task one") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	// the deprecated flags can be combined as well
+	out = run(t, file, "-list", "-active")
+	expected = "[ ] 2: (A) task two\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// exit codes
+	codes := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"help"}, 0},
+		{[]string{"help", "add"}, 0},
+		{[]string{"add", "-h"}, 0},
+		{[]string{"done", "9"}, 1},
+		{[]string{"frobnicate"}, 2},
+		{[]string{"done"}, 2},
+		{[]string{"list", "-bogus"}, 2},
+		{[]string{"add", "-priority", "urgent", "task"}, 2},
+		{[]string{}, 2},
+	}
+	for _, tc := range codes {
+		if out, code := runCode(t, file, tc.args...); code != tc.code {
+			t.Errorf("%v: expected exit code %d; got %d instead: %s", tc.args, tc.code, code, out)
+		}
+	}
+}
+
+// TestTodoCLIFilter will combine the filter flags of the list command
+func TestTodoCLIFilter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "-priority", "A", "buy milk")
+	run(t, file, "add", "-priority", "C", "write report")
+	run(t, file, "add", "buy bread")
+	run(t, file, "done", "3")
+
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"list", "-match", "buy", "-active"}, "[ ] 1: (A) buy milk\n"},
+		{[]string{"list", "-priority", "A-B,none"}, "[ ] 1: (A) buy milk\n[x] 3: buy bread\n"},
+		{[]string{"list", "-done", "-completed-after", "today"}, "[x] 3: buy bread\n"},
+		{[]string{"list", "-created-before", "yesterday"}, ""},
+	}
+	for _, tc := range cases {
+		if out := run(t, file, tc.args...); out != tc.expected {
+			t.Errorf("%v: expected %q; got %q instead", tc.args, tc.expected, out)
+		}
+	}
+}
+
+// TestTodoCLITags will add tagged tasks, filter by tag and change the tags of a task
+func TestTodoCLITags(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "deploy +api @work")
+	run(t, file, "add", "-tag", "+web", "fix css")
+	run(t, file, "add", "buy milk @shop")
+
+	out := run(t, file, "list", "-tag", "+api")
+	expected := "[ ] 1: deploy +api @work\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	run(t, file, "edit", "-tag", "@work", "-untag", "+web", "2")
+	out = run(t, file, "list", "-tag", "@work")
+	expected = "[ ] 1: deploy +api @work\n[ ] 2: fix css @work\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the tags are stored in their own field
+	l := todo.List{}
+	if err := l.Get(file); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Task != "deploy" {
+		t.Errorf("expected %q; got %q instead", "deploy", l[0].Task)
+	}
+}
+
+// TestTodoCLIEdit will rename a task from the arguments and from an editor
+func TestTodoCLIEdit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "wirte docs +docs")
+	run(t, file, "add", "second")
+
+	run(t, file, "edit", "1", "write", "docs")
+	out := run(t, file, "list")
+	expected := "[ ] 1: write docs +docs\n[ ] 2: second\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the editor is a script that replaces the file contents
+	if runtime.GOOS == "windows" {
+		t.Skip("editor script needs a unix shell")
+	}
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\necho 'write the docs +docs @home' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", editor)
+
+	run(t, file, "edit", "-e", "1")
+	out = run(t, file, "list")
+	expected = "[ ] 1: write the docs +docs @home\n[ ] 2: second\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	out = run(t, file, "list", "-verbose")
+	if !strings.Contains(out, "| Modified: ") {
+		t.Errorf("expected the modified date in %q", out)
+	}
+
+	// a VISUAL of blanks is ignored, falling back to EDITOR
+	t.Setenv("VISUAL", "  ")
+	t.Setenv("EDITOR", editor)
+	run(t, file, "edit", "-e", "2")
+	if out := run(t, file, "list"); !strings.HasSuffix(out, "[ ] 2: write the docs +docs @home\n") {
+		t.Errorf("expected the second task to be edited; got %q", out)
+	}
+}
+
+// TestTodoCLIUndo will undo and redo a deletion, list the history and reopen a task
+func TestTodoCLIUndo(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "first")
+	run(t, file, "add", "second")
+	run(t, file, "add", "third")
+	run(t, file, "rm", "2")
+
+	out := run(t, file, "undo")
+	if !strings.Contains(out, `- "second"`) {
+		t.Errorf("expected the undone change in %q", out)
+	}
+	expected := "[ ] 1: first\n[ ] 2: second\n[ ] 3: third\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	run(t, file, "redo")
+	expected = "[ ] 1: first\n[ ] 2: third\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	out = run(t, file, "history")
+	if !strings.HasPrefix(out, "4: ") || !strings.Contains(out, "rm\n    - \"second\"") {
+		t.Errorf("unexpected history %q", out)
+	}
+
+	// reopen a completed task
+	run(t, file, "done", "1")
+	run(t, file, "reopen", "1")
+	expected = "[ ] 1: first\n[ ] 2: third\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+	if out := run(t, file, "list", "-verbose"); !strings.Contains(out, "| Reopened: ") {
+		t.Errorf("expected the reopen date in %q", out)
+	}
+	if _, code := runCode(t, file, "reopen", "2"); code != 1 {
+		t.Errorf("expected exit code %d reopening an active task; got %d instead", 1, code)
+	}
+
+	// nothing left to redo
+	if _, code := runCode(t, file, "redo"); code != 1 {
+		t.Errorf("expected exit code %d; got %d instead", 1, code)
+	}
+}
+
+// TestTodoCLIBulk will complete, tag and delete ranges of tasks and tasks picked by filters
+func TestTodoCLIBulk(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	for i := 1; i <= 6; i++ {
+		run(t, file, "add", fmt.Sprintf("task %d", i))
+	}
+
+	run(t, file, "done", "1-3,5")
+	run(t, file, "tag", "4,6", "+later")
+	run(t, file, "tag", "-done", "@finished")
+
+	// every done task older than a day: none yet
+	run(t, file, "rm", "-done", "-older-than", "1d")
+	out := run(t, file, "list")
+	expected := "[x] 1: task 1 @finished\n[x] 2: task 2 @finished\n[x] 3: task 3 @finished\n[ ] 4: task 4 +later\n[x] 5: task 5 @finished\n[ ] 6: task 6 +later\n"
+	if expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the selection and the filter are combined
+	run(t, file, "rm", "-done", "1-4")
+	expected = "[ ] 1: task 4 +later\n[x] 2: task 5 @finished\n[ ] 3: task 6 +later\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the whole bulk delete is a single step of the history
+	run(t, file, "undo")
+	if out := run(t, file, "list"); !strings.HasPrefix(out, "[x] 1: task 1") {
+		t.Errorf("expected the deleted tasks back; got %q", out)
+	}
+
+	// a bulk command needs a selection or a filter
+	if _, code := runCode(t, file, "rm"); code != 2 {
+		t.Errorf("expected exit code %d; got %d instead", 2, code)
+	}
+}
+
+// TestTodoCLIArchive will archive the completed tasks, search the archive and restore a task
+func TestTodoCLIArchive(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "pay rent")
+	run(t, file, "add", "buy milk")
+	run(t, file, "add", "call bank")
+	run(t, file, "done", "1,3")
+
+	// tasks completed before yesterday: none
+	if out := run(t, file, "archive", "-completed-before", "yesterday"); out != "Archived 0 task(s)\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	run(t, file, "archive")
+	expected := "[ ] 1: buy milk\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	expected = "[x] 2: call bank\n"
+	if out := run(t, file, "archived", "-match", "bank"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	run(t, file, "restore", "2")
+	expected = "[ ] 1: buy milk\n[x] 2: call bank\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+	expected = "[x] 1: pay rent\n"
+	if out := run(t, file, "archived"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// subtasks are archived along with their parent
+	file = filepath.Join(t.TempDir(), "todo.json")
+	run(t, file, "add", "parent")
+	run(t, file, "add", "-parent", "1", "child 1")
+	run(t, file, "add", "-parent", "1", "child 2")
+	run(t, file, "done", "1.1")
+	run(t, file, "done", "1.2")
+	if out := run(t, file, "archive", "1"); out != "Archived 3 task(s)\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	expected = "[x] 1: parent\n  [x] 1.1: child 1\n  [x] 1.2: child 2\n"
+	if out := run(t, file, "archived"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+	run(t, file, "restore", "1")
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+}
+
+// TestTodoCLIArchiveUndo will undo and redo archiving and restoring tasks, checking both the list and the archive
+func TestTodoCLIArchiveUndo(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	// check compares the list and the archive with the expected tasks
+	check := func(list, archived string) {
+		t.Helper()
+		if out := run(t, file, "list"); out != list {
+			t.Errorf("expected the list %q; got %q instead", list, out)
+		}
+		if out := run(t, file, "archived"); out != archived {
+			t.Errorf("expected the archive %q; got %q instead", archived, out)
+		}
+	}
+
+	run(t, file, "add", "one")
+	run(t, file, "done", "1")
+	run(t, file, "archive")
+	check("", "[x] 1: one\n")
+
+	// undoing the archive moves the task back to the list
+	run(t, file, "undo")
+	check("[x] 1: one\n", "")
+	run(t, file, "redo")
+	check("", "[x] 1: one\n")
+
+	// undoing the restore moves the task back to the archive
+	run(t, file, "restore", "1")
+	check("[x] 1: one\n", "")
+	run(t, file, "undo")
+	check("", "[x] 1: one\n")
+
+	// and undoing the archive after that puts it back on the list
+	run(t, file, "undo")
+	check("[x] 1: one\n", "")
+}
+
+// TestTodoCLIRecur will complete a recurring task and check the next occurrence is added
+func TestTodoCLIRecur(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "-every", "every:3d", "-due", "2100-01-01", "water plants")
+	run(t, file, "done", "1")
+
+	expected := "[x] 1: water plants\n[ ] 2: water plants\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+	out := run(t, file, "list", "-active", "-verbose")
+	if !strings.Contains(out, "Due: 2100-01-04 | Repeats: every:3d") {
+		t.Errorf("expected the next due date and recurrence in %q", out)
+	}
+
+	// stop it from repeating
+	run(t, file, "edit", "-every", "none", "2")
+	run(t, file, "done", "2")
+	if out := run(t, file, "list"); strings.Count(out, "\n") != 2 {
+		t.Errorf("expected no new occurrence; got %q", out)
+	}
+
+	if _, code := runCode(t, file, "add", "-every", "hourly", "task"); code != 2 {
+		t.Errorf("expected exit code %d; got %d instead", 2, code)
+	}
+}
+
+// TestTodoCLISubtasks will add subtasks, refer to them by their number and check the completion and delete rules
+func TestTodoCLISubtasks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "plan trip")
+	run(t, file, "add", "buy milk")
+	run(t, file, "add", "-parent", "1", "book flights")
+	run(t, file, "add", "-parent", "1", "book hotel")
+
+	expected := "[ ] 1: plan trip\n  [ ] 1.1: book flights\n  [ ] 1.2: book hotel\n[ ] 2: buy milk\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the parent can't be completed while it's subtasks are open
+	if _, code := runCode(t, file, "done", "1"); code != 1 {
+		t.Errorf("expected exit code %d; got %d instead", 1, code)
+	}
+	run(t, file, "done", "1.1,1.2")
+	expected = "[x] 1: plan trip\n  [x] 1.1: book flights\n  [x] 1.2: book hotel\n[ ] 2: buy milk\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// move buy milk under plan trip and delete the whole tree
+	run(t, file, "reopen", "1.1")
+	run(t, file, "edit", "-parent", "1", "2")
+	run(t, file, "rm", "1")
+	if out := run(t, file, "list"); out != "" {
+		t.Errorf("expected an empty list; got %q", out)
+	}
+}
+
+// TestTodoCLIDependencies will block a task by another one, list the blocked and ready tasks and complete them
+func TestTodoCLIDependencies(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, file, "add", "run migration")
+	run(t, file, "add", "-blocked-by", "1", "deploy")
+	run(t, file, "add", "write docs")
+
+	expected := "[ ] 2: deploy\n"
+	if out := run(t, file, "list", "-blocked"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+	expected = "[ ] 1: run migration\n[ ] 3: write docs\n"
+	if out := run(t, file, "list", "-ready"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// loops are refused
+	if _, code := runCode(t, file, "edit", "-blocked-by", "2", "1"); code != 1 {
+		t.Errorf("expected exit code %d; got %d instead", 1, code)
+	}
+
+	// blocked tasks aren't completed unless forced or completed along with their blockers
+	if _, code := runCode(t, file, "done", "2"); code != 1 {
+		t.Errorf("expected exit code %d; got %d instead", 1, code)
+	}
+	run(t, file, "done", "1,2")
+
+	run(t, file, "edit", "-blocked-by", "3", "-unblock", "1", "2")
+	run(t, file, "reopen", "2")
+	out, code := runCode(t, file, "done", "-force", "2")
+	if code != 0 || !strings.Contains(out, "warning: completing \"deploy\"") {
+		t.Errorf("expected a warning; got %q (exit code %d)", out, code)
+	}
+}
+
+// TestTodoCLINotes will add a task with notes read from STDIN and show them
+func TestTodoCLINotes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	// the first line is the task, the rest are the notes
+	runStdin(t, file, "plan trip\nbook flights first\ncheck passports\n", "add")
+
+	if out := run(t, file, "list"); out != "[ ] 1: plan trip\n" {
+		t.Errorf("unexpected list %q", out)
+	}
+	out := run(t, file, "list", "-verbose")
+	if !strings.HasSuffix(out, "\n    book flights first\n    check passports\n") {
+		t.Errorf("expected the notes in %q", out)
+	}
+	out = run(t, file, "show", "1")
+	if !strings.HasPrefix(out, "Task:       plan trip\n") || !strings.HasSuffix(out, "\n  book flights first\n  check passports\n") {
+		t.Errorf("unexpected details %q", out)
+	}
+
+	run(t, file, "edit", "-notes", "none", "1")
+	if out := run(t, file, "show", "1"); strings.Contains(out, "passports") {
+		t.Errorf("expected the notes to be removed from %q", out)
+	}
+	if _, code := runCode(t, file, "show"); code != 2 {
+		t.Errorf("expected exit code %d; got %d instead", 2, code)
+	}
+}
+
+// TestTodoCLITodoTxt will export the list to a todo.txt file, change it as another tool would and import it back
+func TestTodoCLITodoTxt(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "todo.json")
+	txt := filepath.Join(dir, "todo.txt")
+
+	run(t, file, "add", "-priority", "A", "pay rent +home")
+	run(t, file, "add", "call bank")
+	run(t, file, "export", "-format", "todotxt", "-o", txt)
+
+	data, err := os.ReadFile(txt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "(A) ") || !strings.Contains(lines[0], " pay rent +home created:") {
+		t.Fatalf("unexpected export %q", data)
+	}
+
+	// the same file imports without changes
+	if out := run(t, file, "import", txt); out != "Imported 0 new task(s), updated 0\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	// complete a task and add another one, as a phone app would
+	lines[1] = "x 2026-10-17 " + lines[1]
+	lines = append(lines, "(C) buy milk @shop")
+	if err := os.WriteFile(txt, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out := run(t, file, "import", txt); out != "Imported 1 new task(s), updated 1\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	expected := "[ ] 1: (A) pay rent +home\n[x] 2: call bank\n[ ] 3: (C) buy milk @shop\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	if _, code := runCode(t, file, "export", "-format", "yaml"); code != 2 {
+		t.Errorf("expected exit code %d; got %d instead", 2, code)
+	}
+}
+
+// TestTodoCLIExport will export the list as a Markdown checklist and as a CSV file
+func TestTodoCLIExport(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "todo.json")
+
+	run(t, file, "add", "pay rent")
+	run(t, file, "add", "buy milk @shop")
+	run(t, file, "done", "1")
+
+	expected := "- [x] pay rent\n- [ ] buy milk @shop\n"
+	if out := run(t, file, "export", "-format", "markdown"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the filter flags select what's exported
+	csvFile := filepath.Join(dir, "todo.csv")
+	run(t, file, "export", "-format", "csv", "-active", "-o", csvFile)
+	data, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "position,id,task,done,") || !strings.Contains(lines[1], ",buy milk,false,,@shop,") {
+		t.Errorf("unexpected CSV %q", data)
+	}
+}
+
+// TestTodoCLIBatchAdd will pipe a file of tasks into the add command, one task per line
+func TestTodoCLIBatchAdd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	out := runStdin(t, file, "(A) pay rent +home due:+3d\nbuy milk @shop\n\ncall bank\n", "add", "-batch")
+	if out != "Added 3 task(s)\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	expected := "[ ] 1: (A) pay rent +home\n[ ] 2: buy milk @shop\n[ ] 3: call bank\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the batch is a single step in the history
+	run(t, file, "undo")
+	if out := run(t, file, "list"); out != "" {
+		t.Errorf("expected an empty list; got %q", out)
+	}
+
+	if _, code := runCode(t, file, "add", "-batch", "-priority", "A"); code != 2 {
+		t.Errorf("expected exit code %d; got %d instead", 2, code)
+	}
+}
+
+// TestTodoCLIJSON will run commands with -json and decode the documents they print
+func TestTodoCLIJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	// the fields of the document the test checks
+	type jsonDoc struct {
+		Version int
+		Command string
+		Error   string
+		Items   []struct {
+			Position  int
+			Task      string
+			Done      bool
+			Tags      []string
+			Created   string
+			Completed string
+			Change    string
+		}
+	}
+
+	// decode runs the command and decodes it's output
+	decode := func(code int, command string, args ...string) jsonDoc {
+		t.Helper()
+		out, c := runCode(t, file, args...)
+		if c != code {
+			t.Fatalf("%v: expected exit code %d; got %d: %s", args, code, c, out)
+		}
+		// errors are printed to STDERR as well, before the document
+		if code != 0 {
+			out = out[strings.Index(out, "{"):]
+		}
+		var doc jsonDoc
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("%v: %v: %s", args, err, out)
+		}
+		if doc.Version != 1 || doc.Command != command {
+			t.Errorf("%v: unexpected version %d and command %q", args, doc.Version, doc.Command)
+		}
+		return doc
+	}
+
+	run(t, file, "add", "task 1")
+	doc := decode(0, "add", "add", "-json", "-tag", "+home", "task 2")
+	if len(doc.Items) != 1 || doc.Items[0].Change != "added" || doc.Items[0].Position != 2 || doc.Items[0].Created == "" {
+		t.Errorf("unexpected items %+v", doc.Items)
+	}
+
+	doc = decode(0, "done", "-json", "done", "1")
+	if len(doc.Items) != 1 || doc.Items[0].Change != "completed" || !doc.Items[0].Done || doc.Items[0].Completed == "" {
+		t.Errorf("unexpected items %+v", doc.Items)
+	}
+
+	doc = decode(0, "list", "list", "-json")
+	if len(doc.Items) != 2 || doc.Items[1].Task != "task 2" || doc.Items[1].Tags[0] != "+home" || doc.Items[0].Change != "" {
+		t.Errorf("unexpected items %+v", doc.Items)
+	}
+
+	// deleted tasks keep the position they had
+	doc = decode(0, "rm", "rm", "-json", "1")
+	if len(doc.Items) != 1 || doc.Items[0].Change != "deleted" || doc.Items[0].Position != 1 {
+		t.Errorf("unexpected items %+v", doc.Items)
+	}
+
+	// failures print the error, with the usual exit code
+	doc = decode(1, "rm", "rm", "-json", "5")
+	if doc.Error == "" || len(doc.Items) != 0 {
+		t.Errorf("expected an error and no items; got %+v", doc)
+	}
+
+	// the backups and the doctor have their own fields
+	var backups struct {
+		Backups []struct {
+			Name  string
+			Size  int64
+			Daily bool
+		}
+	}
+	out := run(t, file, "backups", "-json")
+	if err := json.Unmarshal([]byte(out), &backups); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(backups.Backups) == 0 || backups.Backups[0].Name == "" || backups.Backups[0].Size == 0 {
+		t.Errorf("unexpected backups %+v", backups)
+	}
+
+	doc = decode(0, "doctor", "doctor", "-json")
+	if len(doc.Items) != 1 || doc.Items[0].Task != "task 2" {
+		t.Errorf("unexpected items %+v", doc.Items)
+	}
+	if err := os.WriteFile(file, []byte(`{"version":1,"items":[{"ID":"a1b2c3d4","Task":"task 1"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var problems struct {
+		Problems []struct {
+			Position int
+			Message  string
+		}
+	}
+	out, _ = runCode(t, file, "-json", "doctor")
+	if err := json.Unmarshal([]byte(out), &problems); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(problems.Problems) != 1 || problems.Problems[0].Position != 1 || problems.Problems[0].Message != "has no creation date" {
+		t.Errorf("unexpected problems %+v", problems)
+	}
+}
+
+// TestTodoCLIDoctor will damage the list file and repair it with the doctor command
+func TestTodoCLIDoctor(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "todo.json")
+
+	run(t, file, "add", "task 1")
+	run(t, file, "add", "task 2")
+	if out := run(t, file, "doctor"); out != "No problems found\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	// cut the file in the middle of the second task
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := data[:strings.Index(string(data), "task 2")]
+	if err := os.WriteFile(file, damaged, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runCode(t, file, "list"); code != 1 || !strings.Contains(out, "todo doctor") {
+		t.Errorf("expected exit code 1 and a hint to run the doctor; got %d: %q", code, out)
+	}
+	if out, code := runCode(t, file, "doctor", "-n"); code != 1 || !strings.Contains(out, "bad JSON in item 2") {
+		t.Errorf("expected exit code 1 and the problem; got %d: %q", code, out)
+	}
+
+	out := run(t, file, "doctor")
+	if !strings.Contains(out, "Repaired 2 problem(s)") {
+		t.Errorf("unexpected output %q", out)
+	}
+	if out := run(t, file, "list"); out != "[ ] 1: task 1\n" {
+		t.Errorf("expected the first task to be salvaged; got %q", out)
+	}
+
+	// the damaged file is kept
+	backups, err := filepath.Glob(file + ".*.bak")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected 1 copy of the file; got %v, %v", backups, err)
+	}
+	if kept, err := os.ReadFile(backups[0]); err != nil || string(kept) != string(damaged) {
+		t.Errorf("expected the copy to hold the damaged file; got %q, %v", kept, err)
+	}
+
+	// garbage after the list is a problem too, even though every task can be read
+	data, err = os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, append(data, "}}garbage"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, code := runCode(t, file, "doctor", "-n"); code != 1 {
+		t.Errorf("expected exit code %d; got %d instead", 1, code)
+	}
+	run(t, file, "doctor")
+	if out := run(t, file, "list"); out != "[ ] 1: task 1\n" {
+		t.Errorf("expected the file to be repaired; got %q", out)
+	}
+
+	// the journal store can't salvage a damaged list, so the doctor refuses to run on it
+	journal := filepath.Join(t.TempDir(), "todo.journal")
+	run(t, journal, "-store", "journal", "add", "task 1")
+	if out, code := runCode(t, journal, "-store", "journal", "doctor"); code != 2 || !strings.Contains(out, `"journal" store`) {
+		t.Errorf("expected exit code 2 and a usage error; got %d: %q", code, out)
+	}
+}
+
+// TestTodoCLIBackups will save the list a few times, list the backups and roll back to one of them
+func TestTodoCLIBackups(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+
+	if out := run(t, file, "backups"); out != "No backups\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	for _, task := range []string{"task 1", "task 2", "task 3", "task 4"} {
+		run(t, file, "-backups", "2", "add", task)
+	}
+
+	// the most recent backup is listed first
+	lines := strings.Split(strings.TrimSpace(run(t, file, "backups")), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 backups; got %q", lines)
+	}
+	oldest := strings.Fields(lines[1])[0]
+
+	out := run(t, file, "restore", "-backup", oldest)
+	if out != "Rolled the list back to backup "+oldest+" (2 task(s))\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	expected := "[ ] 1: task 1\n[ ] 2: task 2\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	// the roll back can be undone
+	run(t, file, "undo")
+	if out := run(t, file, "list"); !strings.Contains(out, "4: task 4") {
+		t.Errorf("expected the 4 tasks back; got %q", out)
+	}
+
+	// a damaged list can be rolled back as well
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, code := runCode(t, file, "restore", "-backup", oldest)
+	if code != 0 || !strings.Contains(out, "Rolled the list back to backup "+oldest) {
+		t.Errorf("expected the damaged list to be rolled back; got %d: %q", code, out)
+	}
+	expected = "[ ] 1: task 1\n[ ] 2: task 2\n"
+	if out := run(t, file, "list"); expected != out {
+		t.Errorf("expected %q; got %q instead\n", expected, out)
+	}
+
+	if _, code := runCode(t, file, "-backups", "weekly", "list"); code != 2 {
+		t.Errorf("expected exit code %d; got %d instead", 2, code)
+	}
+	if _, code := runCode(t, file, "restore", "-backup", "nope"); code != 1 {
+		t.Errorf("expected exit code %d; got %d instead", 1, code)
+	}
+}
+
 // ===============================
 // CLEAR
 // ===============================
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/dupakarovsky/todo"
+)
+
+//=================================
+// JSON OUTPUT
+//=================================
+// With -json the commands print a single JSON document instead of their text output, so scripts don't have to
+// scrape it. The document is versioned: fields are only ever added within a version. eg.:
+//   {"version": 1, "command": "done", "items": [{"position": 2, "number": "2", "id": "a1b2c3d4", "task": "buy milk",
+//    "done": true, "tags": [], "created": "...", "completed": "...", "change": "completed"}]}
+// Listing commands (list, archived, show) return the listed items. The other commands return the items they
+// changed, with the kind of change: added, deleted, completed, reopened or edited. Deleted items keep the position
+// they had. Failures return the error, along with the usual exit code.
+// A few commands add their own field: history the steps it lists, backups the backups of the list (with no items),
+// and doctor the problems it found (left out when there are none) along with the items of the checked, or repaired,
+// list. help and export are the only commands without -json: neither has a result besides it's own output.
+
+// outputVersion is the version of the JSON document. It changes when a field is removed or changes meaning.
+const outputVersion = 1
+
+// output is the JSON document printed in -json mode
+type output struct {
+	Version  int             `json:"version"`
+	Command  string          `json:"command"`
+	Items    []outputItem    `json:"items"`
+	Steps    []outputStep    `json:"steps,omitempty"`
+	Backups  []outputBackup  `json:"backups,omitempty"`
+	Problems []outputProblem `json:"problems,omitempty"`
+	Error    string          `json:"error,omitempty"`
+
+	listed bool // the command set Items to the items it listed, instead of the ones it changed
+}
+
+// outputItem is an item in the JSON document. Dates are in RFC3339 and left out when they're not set.
+type outputItem struct {
+	Position  int      `json:"position"`
+	Number    string   `json:"number,omitempty"`
+	ID        string   `json:"id"`
+	Task      string   `json:"task"`
+	Done      bool     `json:"done"`
+	Priority  string   `json:"priority,omitempty"`
+	Tags      []string `json:"tags"`
+	Due       string   `json:"due,omitempty"`
+	Recur     string   `json:"recur,omitempty"`
+	Parent    string   `json:"parent,omitempty"`
+	BlockedBy []string `json:"blocked_by,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+	Created   string   `json:"created,omitempty"`
+	Completed string   `json:"completed,omitempty"`
+	Modified  string   `json:"modified,omitempty"`
+	Change    string   `json:"change,omitempty"`
+}
+
+// outputStep is a step of the history in the JSON document
+type outputStep struct {
+	Name    string       `json:"name"`
+	At      string       `json:"at"`
+	Undone  bool         `json:"undone"`
+	Changes []outputItem `json:"changes"`
+}
+
+// outputBackup is a backup of the list in the JSON document
+type outputBackup struct {
+	Name  string `json:"name"`
+	Time  string `json:"time"`
+	Size  int64  `json:"size"`
+	Daily bool   `json:"daily"`
+}
+
+// outputProblem is a problem found by the doctor in the JSON document. Problems of the file have no position.
+type outputProblem struct {
+	Position int    `json:"position,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// newOutputItem converts an entry of a View
+func newOutputItem(e todo.Entry) outputItem {
+	tags := append([]string{}, e.Tags...)
+	return outputItem{
+		Position:  e.Pos,
+		Number:    e.Num,
+		ID:        e.ID,
+		Task:      e.Task,
+		Done:      e.Done,
+		Priority:  e.Priority,
+		Tags:      tags,
+		Due:       outputTime(e.Due),
+		Recur:     e.Recur,
+		Parent:    e.Parent,
+		BlockedBy: e.BlockedBy,
+		Notes:     e.Notes,
+		Created:   outputTime(e.CreatedAt),
+		Completed: outputTime(e.CompletedAt),
+		Modified:  outputTime(e.ModifiedAt),
+	}
+}
+
+// outputTime formats a date in RFC3339. Zero dates are left empty
+func outputTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// outputItems converts the entries of a View
+func outputItems(v todo.View) []outputItem {
+	items := []outputItem{}
+	for _, e := range v {
+		items = append(items, newOutputItem(e))
+	}
+	return items
+}
+
+// outputChanges converts the changes of a command. Items still on the list take their number from the View v.
+func outputChanges(changes []todo.ItemChange, v todo.View) []outputItem {
+	// look the numbers up by ID once, rather than scanning the View for every change
+	numbers := make(map[string]string, len(v))
+	for _, e := range v {
+		numbers[e.ID] = e.Num
+	}
+
+	items := []outputItem{}
+	for _, c := range changes {
+		it := newOutputItem(c.Entry())
+		if c.After != nil {
+			it.Number = numbers[c.ID]
+		}
+		it.Change = c.Kind()
+		items = append(items, it)
+	}
+	return items
+}
+
+// outputSteps converts the steps of the history, the undone steps first, like the history command prints them
+func outputSteps(h *todo.History) []outputStep {
+	steps := []outputStep{}
+	add := func(step todo.Step, undone bool) {
+		steps = append(steps, outputStep{
+			Name:    step.Name,
+			At:      outputTime(step.At),
+			Undone:  undone,
+			Changes: outputChanges(step.Changes, nil),
+		})
+	}
+	for _, step := range h.Undone {
+		add(step, true)
+	}
+	for i := len(h.Steps) - 1; i >= 0; i-- {
+		add(h.Steps[i], false)
+	}
+	return steps
+}
+
+// listed records the items listed by a command, to be printed in -json mode
+func (a *app) listed(v todo.View) {
+	if a.result != nil {
+		a.result.Items = outputItems(v)
+		a.result.listed = true
+	}
+}
+
+// listedHistory records the steps of the history listed by the history command, to be printed in -json mode
+func (a *app) listedHistory() {
+	if a.result != nil {
+		a.listed(nil)
+		a.result.Steps = outputSteps(a.history)
+	}
+}
+
+// listedBackups records the backups listed by the backups command, to be printed in -json mode
+func (a *app) listedBackups(backups []todo.Backup) {
+	if a.result != nil {
+		a.listed(nil)
+		a.result.Backups = []outputBackup{}
+		for _, b := range backups {
+			a.result.Backups = append(a.result.Backups, outputBackup{Name: b.Name, Time: outputTime(b.Time), Size: b.Size, Daily: b.Daily})
+		}
+	}
+}
+
+// foundProblems records the problems found by the doctor, to be printed in -json mode
+func (a *app) foundProblems(problems []todo.Problem) {
+	if a.result != nil {
+		a.result.Problems = []outputProblem{}
+		for _, p := range problems {
+			a.result.Problems = append(a.result.Problems, outputProblem{Position: p.Pos, ID: p.ID, Message: p.Msg})
+		}
+	}
+}
+
+// writeResult prints the JSON document of the command. Unless the command listed items, they're the items it
+// changed on the list.
+func (a *app) writeResult(w io.Writer) error {
+	if !a.result.listed && a.list != nil {
+		a.result.Items = outputChanges(todo.Diff(a.before, *a.list), a.list.View())
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a.result)
+}
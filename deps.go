@@ -0,0 +1,118 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+)
+
+//=====================
+// DEPENDENCIES
+//=====================
+// An item can be blocked by other items: "deploy" is blocked by "run migration". The item keeps the IDs of it's
+// blockers in the BlockedBy field. It's blocked while any of them is still open; blockers that are done or no longer
+// on the List don't block it anymore. Links that would make items block each other in a loop are refused.
+
+// Block will make the item on the given position blocked by the item on the blocker position
+func (l *List) Block(pos, blocker int) error {
+	ls := *l
+
+	// check whether the positions passed are valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+	if blocker <= 0 || blocker > len(ls) {
+		return fmt.Errorf("item %d does not exist", blocker)
+	}
+
+	// the blocker can't depend on the item, directly or through other items
+	if blocker == pos || l.dependsOn(blocker, ls[pos-1].ID) {
+		return fmt.Errorf("%q can't be blocked by %q: they would block each other", ls[pos-1].Task, ls[blocker-1].Task)
+	}
+
+	if !slices.Contains(ls[pos-1].BlockedBy, ls[blocker-1].ID) {
+		// build a new slice, so copies of the item aren't changed
+		ls[pos-1].BlockedBy = append(slices.Clip(ls[pos-1].BlockedBy), ls[blocker-1].ID)
+	}
+	return nil
+}
+
+// Unblock will remove the item on the blocker position from the blockers of the item on the given position
+func (l *List) Unblock(pos, blocker int) error {
+	ls := *l
+
+	// check whether the positions passed are valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+	if blocker <= 0 || blocker > len(ls) {
+		return fmt.Errorf("item %d does not exist", blocker)
+	}
+
+	id := ls[blocker-1].ID
+	if !slices.Contains(ls[pos-1].BlockedBy, id) {
+		return fmt.Errorf("%q is not blocked by %q", ls[pos-1].Task, ls[blocker-1].Task)
+	}
+	ls[pos-1].BlockedBy = slices.DeleteFunc(slices.Clone(ls[pos-1].BlockedBy), func(b string) bool { return b == id })
+	if len(ls[pos-1].BlockedBy) == 0 {
+		ls[pos-1].BlockedBy = nil
+	}
+	return nil
+}
+
+// dependsOn reports whether the item on the given position is blocked by the item with the given ID, directly or
+// through it's blockers
+func (l *List) dependsOn(pos int, id string) bool {
+	seen := map[int]bool{}
+	queue := []int{pos}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		for _, b := range (*l)[p-1].BlockedBy {
+			if b == id {
+				return true
+			}
+			if bpos, err := l.Position(b); err == nil {
+				queue = append(queue, bpos)
+			}
+		}
+	}
+	return false
+}
+
+// Blockers returns the positions of the open items blocking the item on the given position
+func (l *List) Blockers(pos int) []int {
+	blockers := []int{}
+	for _, id := range (*l)[pos-1].BlockedBy {
+		if bpos, err := l.Position(id); err == nil && !(*l)[bpos-1].Done {
+			blockers = append(blockers, bpos)
+		}
+	}
+	return blockers
+}
+
+// Blocked returns the active entries of the View that are blocked by an open item of the List l
+func (v View) Blocked(l *List) View {
+	blocked := View{}
+	for _, e := range v {
+		if !e.Done && len(l.Blockers(e.Pos)) > 0 {
+			blocked = append(blocked, e)
+		}
+	}
+	return blocked
+}
+
+// Ready returns the active entries of the View whose blockers on the List l are all done
+func (v View) Ready(l *List) View {
+	ready := View{}
+	for _, e := range v {
+		if !e.Done && len(l.Blockers(e.Pos)) == 0 {
+			ready = append(ready, e)
+		}
+	}
+	return ready
+}
@@ -0,0 +1,222 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+//=====================
+// DOCTOR
+//=====================
+// A List file that was truncated or edited by hand can hold items that can't be right, or not be valid JSON at all.
+// Validate reports the problems of the items, Repair fixes them, and Salvage reads what it can out of a damaged file.
+
+// Problem is something wrong with an item of the List, or with the file it was read from. Pos is 0 for the file.
+type Problem struct {
+	Pos int
+	ID  string
+	Msg string
+}
+
+// String formats the problem. eg.: item 2 (a1b2c3d4): has no creation date
+func (p Problem) String() string {
+	if p.Pos == 0 {
+		return p.Msg
+	}
+	if p.ID == "" {
+		return fmt.Sprintf("item %d: %s", p.Pos, p.Msg)
+	}
+	return fmt.Sprintf("item %d (%s): %s", p.Pos, p.ID, p.Msg)
+}
+
+// Validate will check the items of the List and report every problem found: items without an ID or with the ID of
+// another item, a completion date on an item that isn't done and a missing creation date.
+func (l *List) Validate() []Problem {
+	return l.check(false, time.Time{})
+}
+
+// Repair will fix the problems Validate reports and return them. Items without an ID, or with the ID of an item
+// before them, get a new one. Completion dates are removed from items that aren't done. Items without a creation
+// date get the earliest of their other dates, or now.
+func (l *List) Repair(now time.Time) []Problem {
+	return l.check(true, now)
+}
+
+// check will report the problems of the items, fixing them when repair is set
+func (l *List) check(repair bool, now time.Time) []Problem {
+	ls := *l
+	problems := []Problem{}
+	seen := map[string]int{}
+
+	for idx := range ls {
+		it := &ls[idx]
+		add := func(msg string) {
+			problems = append(problems, Problem{Pos: idx + 1, ID: it.ID, Msg: msg})
+		}
+
+		switch {
+		case it.ID == "":
+			add("has no ID")
+			if repair {
+				it.ID = l.newID()
+			}
+		case seen[it.ID] > 0:
+			add(fmt.Sprintf("has the same ID as item %d", seen[it.ID]))
+			if repair {
+				it.ID = l.newID()
+			}
+		}
+		if seen[it.ID] == 0 {
+			seen[it.ID] = idx + 1
+		}
+
+		if !it.Done && !it.CompletedAt.IsZero() {
+			add("is not done but has a completion date")
+			if repair {
+				it.CompletedAt = time.Time{}
+			}
+		}
+
+		if it.CreatedAt.IsZero() {
+			add("has no creation date")
+			if repair {
+				it.CreatedAt = now
+				for _, t := range []time.Time{it.CompletedAt, it.ModifiedAt} {
+					if !t.IsZero() && t.Before(it.CreatedAt) {
+						it.CreatedAt = t
+					}
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// Salvage will read as many items as it can from the contents of a damaged List file, in any version of the schema.
+// Reading stops at the first invalid JSON, keeping the items before it. Items that are valid JSON but not valid items
+// are dropped, and so is anything after the list. Every item lost, and anything else wrong with the JSON, is reported
+// as a problem of the file.
+func Salvage(data []byte) (List, []Problem) {
+	ls := List{}
+	problems := []Problem{}
+	fail := func(format string, a ...any) (List, []Problem) {
+		return ls, append(problems, Problem{Msg: fmt.Sprintf(format, a...)})
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	envelope, err := findItems(dec)
+	if err != nil {
+		return fail("bad JSON, no items could be read: %v", err)
+	}
+
+	for n := 1; dec.More(); n++ {
+		raw := json.RawMessage{}
+		if err := dec.Decode(&raw); err != nil {
+			return fail("bad JSON in item %d, it and the rest of the file were dropped: %v", n, err)
+		}
+		it := item{}
+		if err := json.Unmarshal(raw, &it); err != nil {
+			problems = append(problems, Problem{Msg: fmt.Sprintf("item %d was dropped: %v", n, err)})
+			continue
+		}
+		ls = append(ls, it)
+	}
+
+	// the closing bracket is missing when the file was truncated between two items
+	if _, err := dec.Token(); err != nil {
+		return fail("bad JSON, the file ends after item %d: %v", len(ls), err)
+	}
+
+	// the rest of the envelope, and nothing after it
+	if envelope {
+		if err := skipObject(dec); err != nil {
+			return fail("bad JSON after the items: %v", err)
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fail("bad JSON after the list, the rest of the file was dropped: %v", err)
+	}
+	return ls, problems
+}
+
+// skipObject will move the decoder past the closing brace of the object it's in, skipping the keys left
+func skipObject(dec *json.Decoder) error {
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		if err := dec.Decode(&json.RawMessage{}); err != nil {
+			return err
+		}
+	}
+	tok, err := dec.Token()
+	if err == nil && tok != json.Delim('}') {
+		err = fmt.Errorf("unexpected %v", tok)
+	}
+	return err
+}
+
+// findItems will move the decoder to the start of the array of items: the file itself for version 0 of the schema,
+// or the items of the envelope. envelope reports which one it was.
+func findItems(dec *json.Decoder) (envelope bool, err error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == json.Delim('[') {
+		return false, nil
+	}
+	if tok != json.Delim('{') {
+		return false, errors.New("the file doesn't hold a list")
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return true, err
+		}
+		if key != "items" {
+			// skip the value of the other keys
+			if err := dec.Decode(&json.RawMessage{}); err != nil {
+				return true, err
+			}
+			continue
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return true, errors.New("the items aren't an array")
+		}
+		return true, nil
+	}
+	return true, errors.New("the file has no items")
+}
+
+// Salvage will read the store's file into the List like Load, salvaging what it can when the file is damaged (see
+// Salvage). The problems of the file are returned, starting with the reason it couldn't be loaded. Files of a newer
+// version of the schema are refused, as salvaging them would drop the fields this version doesn't know about.
+func (s *JSONStore) Salvage(l *List) ([]Problem, error) {
+	data, err := os.ReadFile(s.Filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if v, err := schemaVersion(data); err == nil && v > SchemaVersion {
+		return nil, newerSchemaError(v)
+	}
+	loadErr := s.Load(l)
+	if loadErr == nil {
+		return nil, nil
+	}
+
+	// the reason the file couldn't be loaded is always a problem, even when every item could be salvaged
+	ls, problems := Salvage(data)
+	*l = ls
+	return append([]Problem{{Msg: loadErr.Error()}}, problems...), nil
+}
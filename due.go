@@ -0,0 +1,166 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//=====================
+// DUE DATES
+//=====================
+
+// dateLayouts are the absolute formats accepted by ParseDate, tried in order
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	time.RFC3339,
+}
+
+// ParseDate will convert a date given by the user (eg.: a due date) into a time, relative to now. It accepts:
+//   - an absolute date or date and time. eg.: 2026-11-01, 2026-11-01 15:04 or RFC3339
+//   - yesterday, today, tomorrow
+//   - an offset in days or weeks from today, forwards or backwards. eg.: +3d, +2w, -7d
+//
+// Dates without a time of day are set to midnight, meaning any time on that day.
+// An empty string or "none" returns the zero time, meaning no date.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	today := startOfDay(now)
+
+	switch strings.ToLower(s) {
+	case "", "none":
+		return time.Time{}, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	// relative offsets: +Nd, +Nw, -Nd or -Nw
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		days, err := parseDays(strings.ToLower(s[1:]))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		if s[0] == '-' {
+			days = -days
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	// absolute dates are in the local time zone, unless they carry their own
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseDays converts a count of days or weeks (eg.: 3d, 2w) into a number of days
+func parseDays(s string) (int, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid span %q", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid span %q", s)
+	}
+
+	switch s[len(s)-1] {
+	case 'd':
+		return n, nil
+	case 'w':
+		return n * 7, nil
+	}
+	return 0, fmt.Errorf("invalid span %q", s)
+}
+
+// ParseSpan will convert a span of time given by the user into a time.Duration. It accepts days and weeks
+// (eg.: 7d, 2w) as well as anything time.ParseDuration accepts (eg.: 36h).
+func ParseSpan(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if days, err := parseDays(s); err == nil {
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid span %q", s)
+	}
+	return d, nil
+}
+
+// startOfDay returns midnight of the day of t
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// FormatDue will format a due date to be displayed. The time of day is left out for dates due at midnight.
+func FormatDue(t time.Time) string {
+	if t.Equal(startOfDay(t)) {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// deadline returns the moment the item becomes overdue. Items due at midnight can be done at any time on that day,
+// so they're only overdue once the day is over.
+func (i item) deadline() time.Time {
+	if i.Due.Equal(startOfDay(i.Due)) {
+		return i.Due.AddDate(0, 0, 1)
+	}
+	return i.Due
+}
+
+// Overdue reports whether the item is still active and it's due date has passed
+func (i item) Overdue(now time.Time) bool {
+	return !i.Done && !i.Due.IsZero() && !now.Before(i.deadline())
+}
+
+// SetDue will change the due date of the item on the given position. The zero time removes the due date.
+func (l *List) SetDue(pos int, due time.Time) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	ls[pos-1].Due = due
+	return nil
+}
+
+// Overdue returns the entries of the View that are overdue at now
+func (v View) Overdue(now time.Time) View {
+	overdue := View{}
+	for _, e := range v {
+		if e.Overdue(now) {
+			overdue = append(overdue, e)
+		}
+	}
+	return overdue
+}
+
+// DueWithin returns the active entries of the View that aren't overdue yet, but are due within the span from now
+func (v View) DueWithin(now time.Time, span time.Duration) View {
+	upcoming := View{}
+	limit := now.Add(span)
+	for _, e := range v {
+		if e.Done || e.Due.IsZero() || e.Overdue(now) {
+			continue
+		}
+		if e.Due.Before(limit) {
+			upcoming = append(upcoming, e)
+		}
+	}
+	return upcoming
+}
@@ -0,0 +1,72 @@
+package todo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Changes describes the edits to make to an item. Fields left nil (or empty, for the tags) aren't changed.
+type Changes struct {
+	Task       *string
+	Priority   *string // parsed with ParsePriority. "none" removes the priority
+	Due        *time.Time
+	Recur      *string // parsed with ParseRecurrence. "none" stops the item from repeating
+	Notes      *string // cleaned with CleanNotes. Empty notes remove them
+	AddTags    []string
+	RemoveTags []string // removed before AddTags are added
+}
+
+// Edit will apply the changes to the item on the given position, keeping it's ID, position and creation date.
+// When anything actually changes, ModifiedAt is set to the current time. Nothing is changed if any of the
+// changes is invalid.
+func (l *List) Edit(pos int, c Changes) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	// work on a copy, so an invalid change leaves the item untouched
+	edited := List{ls[pos-1]}
+
+	if c.Task != nil {
+		task := strings.TrimSpace(*c.Task)
+		if task == "" {
+			return fmt.Errorf("Task cannot be blank")
+		}
+		edited[0].Task = task
+	}
+	if c.Priority != nil {
+		if err := edited.SetPriority(1, *c.Priority); err != nil {
+			return err
+		}
+	}
+	if c.Due != nil {
+		edited.SetDue(1, *c.Due)
+	}
+	if c.Recur != nil {
+		if err := edited.SetRecurrence(1, *c.Recur); err != nil {
+			return err
+		}
+	}
+	if c.Notes != nil {
+		edited.SetNotes(1, *c.Notes)
+	}
+	edited.RemoveTags(1, c.RemoveTags...)
+	edited.AddTags(1, c.AddTags...)
+
+	// RemoveTags leaves nil tags when there are none. Don't count that as a change
+	if len(edited[0].Tags) == 0 && len(ls[pos-1].Tags) == 0 {
+		edited[0].Tags = ls[pos-1].Tags
+	}
+	if reflect.DeepEqual(edited[0], ls[pos-1]) {
+		return nil
+	}
+
+	edited[0].ModifiedAt = time.Now()
+	ls[pos-1] = edited[0]
+	return nil
+}
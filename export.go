@@ -0,0 +1,81 @@
+package todo
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//=====================
+// EXPORT
+//=====================
+// Views can be written as CSV, for spreadsheets, or as a Markdown checklist, for reports. Both keep the order and
+// numbers the View is displayed with.
+
+// csvHeader holds the names of the CSV columns
+var csvHeader = []string{"position", "id", "task", "done", "priority", "tags", "due", "created", "completed", "notes"}
+
+// WriteCSV will write the entries of the View to w as CSV, with a header line. Dates are written in RFC3339 and
+// left empty when they're not set.
+func (v View) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range v {
+		record := []string{
+			e.number(),
+			e.ID,
+			e.Task,
+			strconv.FormatBool(e.Done),
+			e.Priority,
+			strings.Join(e.Tags, " "),
+			csvTime(e.Due),
+			csvTime(e.CreatedAt),
+			csvTime(e.CompletedAt),
+			e.Notes,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvTime formats a date for the CSV export. Zero dates are left empty
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// WriteMarkdown will write the entries of the View to w as a Markdown checklist. eg.: - [x] (A) Pay rent +home
+// Subtasks are nested under their parent and notes are written below their task.
+func (v View) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range v {
+		check := " "
+		if e.Done {
+			check = "x"
+		}
+		line := fmt.Sprintf("%s- [%s] %s%s", e.indent(), check, e.priorityTag(), e.text())
+		if !e.Due.IsZero() {
+			line += " (due " + FormatDue(e.Due) + ")"
+		}
+		fmt.Fprintln(bw, line)
+
+		// indent the notes past the dash, so they're part of the list item
+		if e.Notes != "" {
+			fmt.Fprint(bw, e.notesBlock(e.indent()+"  "))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,85 @@
+package todo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultFileMode is the permission used when a List file is created for the first time
+const defaultFileMode fs.FileMode = 0644
+
+// writeFile will write data to filename atomically. The data is written to a temporary file in the same directory,
+// flushed to disk and then renamed over filename. If anything fails along the way the original file is left untouched.
+// The permissions of an existing file are preserved; new files are created with defaultFileMode.
+func writeFile(filename string, data []byte) error {
+	// keep the permissions of the file we're replacing, if there's one
+	mode := defaultFileMode
+	info, err := os.Stat(filename)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	// the temp file must live in the same directory, as rename is only atomic within the same file system
+	temp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// remove the temp file if we return before renaming it. After the rename this is a no-op
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	// make sure the contents reach the disk before the rename makes them visible
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(mode); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), filename)
+}
+
+// FileLock is an advisory lock held over a List file. It's used to serialize the Get -> modify -> Save cycle
+// between processes working on the same file.
+type FileLock struct {
+	file *os.File
+}
+
+// Lock will take an exclusive advisory lock for filename, blocking until it's available. The lock is held on a
+// separate filename.lock file, as Save replaces the List file itself on every call.
+func Lock(filename string) (*FileLock, error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, defaultFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &FileLock{file: f}, nil
+}
+
+// Unlock will release the lock. Closing the file releases it as well, so a process that exits while holding the
+// lock never leaves it behind.
+func (fl *FileLock) Unlock() error {
+	if err := unlockFile(fl.file); err != nil {
+		fl.file.Close()
+		return err
+	}
+	return fl.file.Close()
+}
@@ -0,0 +1,158 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+//=====================
+// FILTERS
+//=====================
+
+// Status selects items by whether they're done
+type Status int
+
+const (
+	StatusAny    Status = iota // every item
+	StatusActive               // items that aren't done
+	StatusDone                 // completed items
+)
+
+// ParseStatus converts a status given by the user (all, active or done) into a Status
+func ParseStatus(s string) (Status, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "all", "any":
+		return StatusAny, nil
+	case "active", "open":
+		return StatusActive, nil
+	case "done", "completed":
+		return StatusDone, nil
+	}
+	return StatusAny, fmt.Errorf("invalid status %q", s)
+}
+
+// Filter selects items of a List. Every field that is set must match; fields left in their zero value don't
+// filter anything. Date ranges include their After bound and exclude their Before bound.
+type Filter struct {
+	Status Status
+	// Text matches items whose task or tags contain it, ignoring case
+	Text string
+	// Priorities matches items with any of the priorities. An empty string in it matches items without a priority
+	Priorities []string
+	// Tags matches items that have every one of the tags, ignoring case
+	Tags []string
+
+	CreatedAfter    time.Time
+	CreatedBefore   time.Time
+	CompletedAfter  time.Time
+	CompletedBefore time.Time
+}
+
+// IsZero reports whether the Filter has no conditions, matching every item
+func (f Filter) IsZero() bool {
+	return f.Status == StatusAny && f.Text == "" && len(f.Priorities) == 0 && len(f.Tags) == 0 &&
+		f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero() && f.CompletedAfter.IsZero() && f.CompletedBefore.IsZero()
+}
+
+// Match reports whether the item passes every condition of the Filter
+func (f Filter) Match(i item) bool {
+	switch f.Status {
+	case StatusActive:
+		if i.Done {
+			return false
+		}
+	case StatusDone:
+		if !i.Done {
+			return false
+		}
+	}
+
+	if f.Text != "" && !strings.Contains(strings.ToLower(i.text()), strings.ToLower(f.Text)) {
+		return false
+	}
+
+	if len(f.Priorities) > 0 && !slices.Contains(f.Priorities, i.Priority) {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !i.hasTag(tag) {
+			return false
+		}
+	}
+
+	if !inRange(i.CreatedAt, f.CreatedAfter, f.CreatedBefore) {
+		return false
+	}
+
+	// items that aren't completed have no completion date, so they never match a completion range
+	if !f.CompletedAfter.IsZero() || !f.CompletedBefore.IsZero() {
+		if !i.Done || !inRange(i.CompletedAt, f.CompletedAfter, f.CompletedBefore) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inRange reports whether t is on or after the after bound and before the before bound. Zero bounds are open.
+func inRange(t, after, before time.Time) bool {
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && !t.Before(before) {
+		return false
+	}
+	return true
+}
+
+// Filter returns a View with the items of the List that match the Filter, keeping their positions
+func (l *List) Filter(f Filter) View {
+	return l.View().Filter(f)
+}
+
+// Filter returns the entries of the View that match the Filter
+func (v View) Filter(f Filter) View {
+	filtered := View{}
+	for _, e := range v {
+		if f.Match(e.item) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// ParsePriorities will convert a comma separated list of priorities into the letters they stand for. Each element
+// is parsed by ParsePriority or can be a range of letters. eg.: "A-C,none" = A, B, C and no priority
+func ParsePriorities(s string) ([]string, error) {
+	priorities := []string{}
+	for _, part := range strings.Split(s, ",") {
+		// a range of letters. eg.: A-C
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			first, err := ParsePriority(from)
+			if err != nil {
+				return nil, err
+			}
+			last, err := ParsePriority(to)
+			if err != nil {
+				return nil, err
+			}
+			if first == "" || last == "" || first > last {
+				return nil, fmt.Errorf("invalid priority range %q", part)
+			}
+			for p := first[0]; p <= last[0]; p++ {
+				priorities = append(priorities, string(p))
+			}
+			continue
+		}
+
+		p, err := ParsePriority(part)
+		if err != nil {
+			return nil, err
+		}
+		priorities = append(priorities, p)
+	}
+	return priorities, nil
+}
@@ -0,0 +1,320 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+//=====================
+// HISTORY
+//=====================
+// The History keeps the last changes made to a List, so they can be undone and redone. Each Step stores the items
+// it changed as they were before and after it, not copies of the whole List.
+
+// DefaultHistorySize is the number of steps a History keeps by default
+const DefaultHistorySize = 50
+
+// ItemChange is the change of a single item in a Step. Before is nil for added items and After is nil for deleted
+// items. Pos and NewPos are the positions of the item before and after the change.
+type ItemChange struct {
+	ID     string
+	Pos    int   `json:",omitempty"`
+	NewPos int   `json:",omitempty"`
+	Before *item `json:",omitempty"`
+	After  *item `json:",omitempty"`
+}
+
+// Step is a change made to the List by a single command. Commands that move items between the List and the archive
+// (see List.Archive) record the changes to the archive in the same Step, so both are undone together.
+type Step struct {
+	Name           string
+	At             time.Time
+	Changes        []ItemChange
+	ArchiveChanges []ItemChange `json:",omitempty"`
+}
+
+// History holds the Steps that can be undone (oldest first) and the Undone steps that can be redone (most
+// recently undone last).
+type History struct {
+	Max    int `json:"-"`
+	Steps  []Step
+	Undone []Step
+}
+
+// NewHistory returns an empty History keeping up to DefaultHistorySize steps
+func NewHistory() *History {
+	return &History{Max: DefaultHistorySize}
+}
+
+// LoadHistory will read the History from filename. A missing file gives an empty History.
+func LoadHistory(filename string) (*History, error) {
+	h := NewHistory()
+
+	data, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return h, nil
+	case err != nil:
+		return nil, err
+	case len(data) == 0:
+		return h, nil
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return h, nil
+}
+
+// Save will write the History to filename
+func (h *History) Save(filename string) error {
+	js, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, js)
+}
+
+// Clone returns a copy of the List that doesn't share any data with it. Used to keep the state of the List before
+// changing it.
+func (l *List) Clone() List {
+	c := make(List, len(*l))
+	for idx, it := range *l {
+		it.Tags = append([]string(nil), it.Tags...)
+		it.Reopened = append([]Reopening(nil), it.Reopened...)
+		it.BlockedBy = append([]string(nil), it.BlockedBy...)
+		it.Extra = append([]string(nil), it.Extra...)
+		c[idx] = it
+	}
+	return c
+}
+
+// Record will add a Step with the differences between the List before and after a command. Nothing is recorded
+// when the List didn't change. Recording a new Step discards the steps that could be redone.
+func (h *History) Record(name string, before, after List) {
+	h.record(Step{Name: name, At: time.Now(), Changes: Diff(before, after)})
+}
+
+// RecordArchive will add a Step like Record, with the differences of the archive as well. It's used by the commands
+// that move items between the List and the archive, so undoing them moves the items back.
+func (h *History) RecordArchive(name string, before, after, archiveBefore, archiveAfter List) {
+	h.record(Step{Name: name, At: time.Now(), Changes: Diff(before, after), ArchiveChanges: Diff(archiveBefore, archiveAfter)})
+}
+
+// record adds the Step, unless it changed nothing
+func (h *History) record(step Step) {
+	if len(step.Changes) == 0 && len(step.ArchiveChanges) == 0 {
+		return
+	}
+
+	h.Steps = append(h.Steps, step)
+	h.Undone = nil
+
+	// drop the oldest steps past the limit
+	if h.Max > 0 && len(h.Steps) > h.Max {
+		h.Steps = h.Steps[len(h.Steps)-h.Max:]
+	}
+}
+
+// Diff returns the changes of every item that was added, deleted or changed between before and after. Items are
+// matched by their ID, looked up once on each side, so it takes linear time on lists of any size.
+func Diff(before, after List) []ItemChange {
+	changes := []ItemChange{}
+	beforeIdx, afterIdx := before.indexByID(), after.indexByID()
+
+	for idx, old := range before {
+		newIdx, ok := afterIdx[old.ID]
+		if !ok {
+			o := old
+			changes = append(changes, ItemChange{ID: old.ID, Pos: idx + 1, Before: &o})
+			continue
+		}
+		if !reflect.DeepEqual(old, after[newIdx]) {
+			o, n := old, after[newIdx]
+			changes = append(changes, ItemChange{ID: old.ID, Pos: idx + 1, NewPos: newIdx + 1, Before: &o, After: &n})
+		}
+	}
+
+	for idx, it := range after {
+		if _, ok := beforeIdx[it.ID]; !ok {
+			n := it
+			changes = append(changes, ItemChange{ID: it.ID, NewPos: idx + 1, After: &n})
+		}
+	}
+
+	return changes
+}
+
+// Undo will revert the last Step on the List and move it to the steps that can be redone
+func (h *History) Undo(l *List) (Step, error) {
+	if len(h.Steps) == 0 {
+		return Step{}, fmt.Errorf("nothing to undo")
+	}
+
+	step := h.Steps[len(h.Steps)-1]
+	h.Steps = h.Steps[:len(h.Steps)-1]
+	step.revert(l)
+	h.Undone = append(h.Undone, step)
+	return step, nil
+}
+
+// Redo will apply again the last undone Step and move it back to the steps that can be undone
+func (h *History) Redo(l *List) (Step, error) {
+	if len(h.Undone) == 0 {
+		return Step{}, fmt.Errorf("nothing to redo")
+	}
+
+	step := h.Undone[len(h.Undone)-1]
+	h.Undone = h.Undone[:len(h.Undone)-1]
+	step.apply(l)
+	h.Steps = append(h.Steps, step)
+	return step, nil
+}
+
+// revert puts the items of the Step back as they were before it
+func (s Step) revert(l *List) {
+	applyChanges(l, s.Changes, func(c ItemChange) (*item, int) { return c.Before, c.Pos })
+}
+
+// apply changes the items of the Step as they were after it
+func (s Step) apply(l *List) {
+	applyChanges(l, s.Changes, func(c ItemChange) (*item, int) { return c.After, c.NewPos })
+}
+
+// RevertArchive puts the items of the archive changed by the Step back as they were before it. Undo only reverts the
+// List, so it's called on the archive after undoing a Step with ArchiveChanges.
+func (s Step) RevertArchive(archive *List) {
+	applyChanges(archive, s.ArchiveChanges, func(c ItemChange) (*item, int) { return c.Before, c.Pos })
+}
+
+// ApplyArchive changes the items of the archive as they were after the Step. It's called on the archive after redoing
+// a Step with ArchiveChanges.
+func (s Step) ApplyArchive(archive *List) {
+	applyChanges(archive, s.ArchiveChanges, func(c ItemChange) (*item, int) { return c.After, c.NewPos })
+}
+
+// applyChanges sets every item of the changes to the state picked by target. A nil state removes the item;
+// items that don't exist are inserted on their position. Insertions are done from the lowest position up, so
+// each item lands on the position it had. The items are looked up once and the List is rebuilt in a single pass,
+// so undoing a change to many items on a long List doesn't take quadratic time.
+func applyChanges(l *List, changes []ItemChange, target func(ItemChange) (*item, int)) {
+	// replace first, marking the items to remove
+	index := l.indexByID()
+	removed := map[int]bool{}
+	inserts := []ItemChange{}
+	for _, c := range changes {
+		state, _ := target(c)
+		idx, ok := index[c.ID]
+		switch {
+		case state == nil && ok:
+			removed[idx] = true
+		case state != nil && ok:
+			(*l)[idx] = *state
+		case state != nil:
+			inserts = append(inserts, c)
+		}
+	}
+
+	rest := List{}
+	for idx, it := range *l {
+		if !removed[idx] {
+			rest = append(rest, it)
+		}
+	}
+
+	sort.SliceStable(inserts, func(i, j int) bool {
+		_, pi := target(inserts[i])
+		_, pj := target(inserts[j])
+		return pi < pj
+	})
+
+	// merge the inserted items in on their positions. Positions past the end of the List, or invalid ones, append
+	// the item at the end
+	merged := make(List, 0, len(rest)+len(inserts))
+	last := []item{}
+	for _, c := range inserts {
+		state, pos := target(c)
+		if pos < 1 {
+			last = append(last, *state)
+			continue
+		}
+		for len(merged) < pos-1 && len(rest) > 0 {
+			merged = append(merged, rest[0])
+			rest = rest[1:]
+		}
+		merged = append(merged, *state)
+	}
+	merged = append(merged, rest...)
+	*l = append(merged, last...)
+}
+
+// String describes the Step, one line per changed item. eg.: + "buy milk"
+func (s Step) String() string {
+	lines := []string{}
+	for _, c := range s.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Kind names the change of the item: added, deleted, completed, reopened or edited
+func (c ItemChange) Kind() string {
+	switch {
+	case c.Before == nil:
+		return "added"
+	case c.After == nil:
+		return "deleted"
+	case !c.Before.Done && c.After.Done:
+		return "completed"
+	case c.Before.Done && !c.After.Done:
+		return "reopened"
+	}
+	return "edited"
+}
+
+// Entry returns the item of the change as an Entry: as it is after the change, on it's new position. Deleted items
+// are returned as they were, on the position they had.
+func (c ItemChange) Entry() Entry {
+	if c.After == nil {
+		return Entry{Pos: c.Pos, item: *c.Before}
+	}
+	return Entry{Pos: c.NewPos, item: *c.After}
+}
+
+// String describes the change of the item: + added, - deleted, x completed, o reopened or ~ edited with the fields
+// that changed
+func (c ItemChange) String() string {
+	switch c.Kind() {
+	case "added":
+		return fmt.Sprintf("+ %q", c.After.text())
+	case "deleted":
+		return fmt.Sprintf("- %q", c.Before.text())
+	case "completed":
+		return fmt.Sprintf("x %q", c.After.text())
+	case "reopened":
+		return fmt.Sprintf("o %q", c.After.text())
+	}
+	if c.Before.Task != c.After.Task {
+		return fmt.Sprintf("~ %q -> %q", c.Before.Task, c.After.Task)
+	}
+
+	// name the fields that changed
+	fields := []string{}
+	bv, av := reflect.ValueOf(*c.Before), reflect.ValueOf(*c.After)
+	for i := 0; i < bv.NumField(); i++ {
+		name := bv.Type().Field(i).Name
+		if name == "ModifiedAt" {
+			continue
+		}
+		if !reflect.DeepEqual(bv.Field(i).Interface(), av.Field(i).Interface()) {
+			fields = append(fields, strings.ToLower(name))
+		}
+	}
+	return fmt.Sprintf("~ %q (%s)", c.After.text(), strings.Join(fields, ", "))
+}
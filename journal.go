@@ -0,0 +1,339 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+//=====================
+// JOURNAL STORAGE
+//=====================
+// The journal store doesn't rewrite the whole List on every Save. Instead it appends one JSON line per change
+// (add, complete, delete, edit) to the journal file and replays them on Load. Every now and then the journal is
+// compacted: the current List is written to a snapshot file and the journal starts over empty.
+
+// DefaultCompactEvery is the number of journal events after which a JournalStore compacts itself
+const DefaultCompactEvery = 500
+
+// journal event operations
+const (
+	opAdd      = "add"
+	opComplete = "complete"
+	opDelete   = "delete"
+	opEdit     = "edit"
+)
+
+// event is a single line of the journal. Seq increases with every event written and is used to skip events that
+// are already part of the snapshot.
+type event struct {
+	Seq  int64      `json:"seq"`
+	Op   string     `json:"op"`
+	ID   string     `json:"id"`
+	Pos  int        `json:"pos,omitempty"`
+	Item *item      `json:"item,omitempty"`
+	At   *time.Time `json:"at,omitempty"`
+}
+
+// snapshot is the format of the compacted List. Seq is the sequence number of the last event folded into it.
+// Version is the SchemaVersion the snapshot was written with. Snapshots written before it was added don't have one.
+type snapshot struct {
+	Version int   `json:"version,omitempty"`
+	Seq     int64 `json:"seq"`
+	Items   List  `json:"items"`
+}
+
+// JournalStore keeps the List as an append-only log of events in Filename, plus a snapshot in Filename.snapshot
+type JournalStore struct {
+	Filename string
+	// CompactEvery is the number of events the journal can hold before Save compacts it. Zero or less disables it.
+	CompactEvery int
+
+	loaded bool
+	seq    int64             // sequence number of the last event
+	events int               // number of events in the journal file
+	size   int64             // size of the valid part of the journal. A torn last line is past this offset
+	ids    []string          // IDs of the List as of the last Load/Save, in order
+	items  map[string][]byte // JSON encoding of each item as of the last Load/Save
+}
+
+// NewJournalStore returns a JournalStore backed by filename
+func NewJournalStore(filename string) *JournalStore {
+	return &JournalStore{Filename: filename, CompactEvery: DefaultCompactEvery}
+}
+
+// snapshotName returns the name of the snapshot file
+func (s *JournalStore) snapshotName() string {
+	return s.Filename + ".snapshot"
+}
+
+// Load will read the snapshot and replay the journal on top of it. A final line that was only partially written
+// (eg.: the process crashed mid write) is ignored.
+func (s *JournalStore) Load(l *List) error {
+	// read the snapshot, if there's one
+	snap := snapshot{}
+	data, err := os.ReadFile(s.snapshotName())
+	switch {
+	case err == nil && len(data) > 0:
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return fmt.Errorf("%s: %w", s.snapshotName(), err)
+		}
+		if snap.Version > SchemaVersion {
+			return fmt.Errorf("%s: %w", s.snapshotName(), newerSchemaError(snap.Version))
+		}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	ls := snap.Items
+	s.seq = snap.Seq
+	s.events = 0
+	s.size = 0
+
+	// replay the journal
+	data, err = os.ReadFile(s.Filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for line := 1; len(data) > 0; line++ {
+		// split off the next line. Events are written together with their newline, so a final line without one
+		// is a partial write left by a crash. It's ignored and overwritten by the next Save.
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+
+		ev := event{}
+		if err := json.Unmarshal(data[:end], &ev); err != nil {
+			return fmt.Errorf("%s:%d: %w", s.Filename, line, err)
+		}
+
+		s.size += int64(end + 1)
+		s.events++
+		data = data[end+1:]
+
+		// skip events already folded into the snapshot
+		if ev.Seq <= snap.Seq {
+			continue
+		}
+		ls = ev.apply(ls)
+		s.seq = ev.Seq
+	}
+
+	// remember the state before giving an ID to any item that was saved without one. This way the next Save
+	// records those items as replaced by their copies with an ID.
+	*l = ls
+	if err := s.remember(l); err != nil {
+		return err
+	}
+	l.ensureIDs()
+	return nil
+}
+
+// apply will replay the event on the List and return the updated List. Events referring to items that no longer
+// exist are ignored.
+func (ev event) apply(ls List) List {
+	pos, err := ls.Position(ev.ID)
+	switch ev.Op {
+	case opAdd:
+		if ev.Item == nil || err == nil {
+			return ls
+		}
+		idx := ev.Pos - 1
+		if idx < 0 || idx > len(ls) {
+			idx = len(ls)
+		}
+		ls = append(ls, item{})
+		copy(ls[idx+1:], ls[idx:])
+		ls[idx] = *ev.Item
+	case opComplete:
+		if err != nil {
+			return ls
+		}
+		ls[pos-1].Done = true
+		if ev.At != nil {
+			ls[pos-1].CompletedAt = *ev.At
+		}
+	case opDelete:
+		if err != nil {
+			return ls
+		}
+		ls = append(ls[:pos-1], ls[pos:]...)
+	case opEdit:
+		if ev.Item == nil || err != nil {
+			return ls
+		}
+		ls[pos-1] = *ev.Item
+	}
+	return ls
+}
+
+// remember will store the state of the List, so the next Save can work out what changed
+func (s *JournalStore) remember(l *List) error {
+	s.ids = make([]string, 0, len(*l))
+	s.items = make(map[string][]byte, len(*l))
+	for _, it := range *l {
+		js, err := json.Marshal(it)
+		if err != nil {
+			return err
+		}
+		s.ids = append(s.ids, it.ID)
+		s.items[it.ID] = js
+	}
+	s.loaded = true
+	return nil
+}
+
+// Save will append an event for every difference between the List and the state of the last Load/Save. If the
+// changes can't be expressed as events (eg.: existing items were reordered) or the journal grew past
+// CompactEvery, the List is compacted into a new snapshot instead.
+func (s *JournalStore) Save(l *List) error {
+	// work out the previous state if Save is called before Load
+	if !s.loaded {
+		if err := s.Load(&List{}); err != nil {
+			return err
+		}
+	}
+
+	events, ok, err := s.diff(l)
+	if err != nil {
+		return err
+	}
+	if !ok || (s.CompactEvery > 0 && s.events+len(events) >= s.CompactEvery) {
+		return s.Compact(l)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	// encode the events as JSON lines
+	buf := bytes.Buffer{}
+	for _, ev := range events {
+		s.seq++
+		ev.Seq = s.seq
+		js, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		buf.Write(js)
+		buf.WriteByte('\n')
+	}
+
+	if err := s.appendJournal(buf.Bytes()); err != nil {
+		return err
+	}
+	s.events += len(events)
+	return s.remember(l)
+}
+
+// appendJournal will write data at the end of the valid part of the journal, dropping a torn last line if there's one
+func (s *JournalStore) appendJournal(data []byte) error {
+	f, err := os.OpenFile(s.Filename, os.O_CREATE|os.O_WRONLY, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// anything past the valid size is a partial line left by a crash
+	if err := f.Truncate(s.size); err != nil {
+		return err
+	}
+	if _, err := f.Seek(s.size, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	s.size += int64(len(data))
+	return f.Close()
+}
+
+// diff will return the events that turn the remembered state into the List. ok is false when existing items
+// changed their relative order, which events can't express.
+func (s *JournalStore) diff(l *List) (events []event, ok bool, err error) {
+	current := make(map[string]bool, len(*l))
+	for _, it := range *l {
+		current[it.ID] = true
+	}
+
+	// items that are gone
+	kept := []string{}
+	for _, id := range s.ids {
+		if !current[id] {
+			events = append(events, event{Op: opDelete, ID: id})
+			continue
+		}
+		kept = append(kept, id)
+	}
+
+	// new and changed items. new items are added in increasing position, so each lands on it's final position
+	k := 0
+	for idx, it := range *l {
+		old, exists := s.items[it.ID]
+		if !exists {
+			added := it
+			events = append(events, event{Op: opAdd, ID: it.ID, Pos: idx + 1, Item: &added})
+			continue
+		}
+
+		// existing items must keep the order they had
+		if k >= len(kept) || kept[k] != it.ID {
+			return nil, false, nil
+		}
+		k++
+
+		js, err := json.Marshal(it)
+		if err != nil {
+			return nil, false, err
+		}
+		if bytes.Equal(js, old) {
+			continue
+		}
+
+		// a change that only marks the item as done is recorded as a complete event
+		prev := item{}
+		if err := json.Unmarshal(old, &prev); err != nil {
+			return nil, false, err
+		}
+		if !prev.Done && it.Done {
+			prev.Done = true
+			prev.CompletedAt = it.CompletedAt
+			if pj, err := json.Marshal(prev); err == nil && bytes.Equal(pj, js) {
+				at := it.CompletedAt
+				events = append(events, event{Op: opComplete, ID: it.ID, At: &at})
+				continue
+			}
+		}
+		edited := it
+		events = append(events, event{Op: opEdit, ID: it.ID, Item: &edited})
+	}
+
+	return events, true, nil
+}
+
+// Compact will write the List to the snapshot file and empty the journal. The snapshot records the sequence number
+// of the last event, so if the process stops before the journal is emptied the old events are skipped on Load.
+func (s *JournalStore) Compact(l *List) error {
+	js, err := json.Marshal(snapshot{Version: SchemaVersion, Seq: s.seq, Items: *l})
+	if err != nil {
+		return err
+	}
+	if err := writeFile(s.snapshotName(), js); err != nil {
+		return err
+	}
+	if err := writeFile(s.Filename, nil); err != nil {
+		return err
+	}
+
+	s.events = 0
+	s.size = 0
+	return s.remember(l)
+}
@@ -0,0 +1,138 @@
+package todo_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dupakarovsky/todo"
+)
+
+// reload is a helper that reads the List back through a brand new JournalStore, like a new CLI run would
+func reload(t *testing.T, filename string) todo.List {
+	t.Helper()
+	l := todo.List{}
+	if err := todo.NewJournalStore(filename).Load(&l); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// TestJournalStore will perform adds, completes, edits and deletes through the journal store, checking that each
+// Save appends to the journal and that replaying it gives back the same List
+func TestJournalStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.journal")
+
+	s := todo.NewJournalStore(filename)
+	l := todo.List{}
+	if err := s.Load(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range []string{"Task 1", "Task 2", "Task 3"} {
+		l.Add(task)
+	}
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	// complete, edit and delete one item each
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	l[2].Task = "Task 3 edited"
+	if err := l.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 adds + complete + edit + delete
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 6 {
+		t.Errorf("expected %d journal lines; got %d instead", 6, n)
+	}
+
+	got := reload(t, filename)
+	if got.String() != l.String() {
+		t.Errorf("expected %q; got %q instead", l.String(), got.String())
+	}
+	if got[1].ID != l[1].ID {
+		t.Errorf("expected ID %q; got %q instead", l[1].ID, got[1].ID)
+	}
+}
+
+// TestJournalTornLine will check that a partially written last line is ignored on Load and replaced on the next Save
+func TestJournalTornLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.journal")
+
+	s := todo.NewJournalStore(filename)
+	l := todo.List{}
+	l.Add("Task 1")
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash in the middle of writing an event
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":2,"op":"add","id":"dead`)
+	f.Close()
+
+	s = todo.NewJournalStore(filename)
+	l = todo.List{}
+	if err := s.Load(&l); err != nil {
+		t.Fatalf("expected the torn line to be ignored; got %v", err)
+	}
+	if len(l) != 1 {
+		t.Fatalf("expected %d item; got %d instead", 1, len(l))
+	}
+
+	// the next save overwrites the torn line
+	l.Add("Task 2")
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+	if got := reload(t, filename); len(got) != 2 {
+		t.Errorf("expected %d items; got %d instead", 2, len(got))
+	}
+}
+
+// TestJournalCompact will check that the journal is folded into the snapshot once it reaches CompactEvery events
+func TestJournalCompact(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.journal")
+
+	s := todo.NewJournalStore(filename)
+	s.CompactEvery = 3
+	l := todo.List{}
+
+	for _, task := range []string{"Task 1", "Task 2", "Task 3", "Task 4"} {
+		l.Add(task)
+		if err := s.Save(&l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the 3rd save compacted the journal, so only the 4th add is left in it
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 1 {
+		t.Errorf("expected %d journal line; got %d instead", 1, n)
+	}
+	if _, err := os.Stat(filename + ".snapshot"); err != nil {
+		t.Errorf("expected a snapshot file: %v", err)
+	}
+
+	if got := reload(t, filename); got.String() != l.String() {
+		t.Errorf("expected %q; got %q instead", l.String(), got.String())
+	}
+}
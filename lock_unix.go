@@ -0,0 +1,18 @@
+//go:build unix
+
+package todo
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive flock on f, waiting for other holders to release it
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the flock held on f
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
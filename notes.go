@@ -0,0 +1,34 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+)
+
+//=====================
+// NOTES
+//=====================
+// Besides it's single line Task, an item can have notes: a free form, multi-line description.
+
+// CleanNotes will tidy up notes before they're stored: trailing spaces are removed from every line, along with the
+// blank lines at the start and at the end. Windows line endings are turned into plain newlines.
+func CleanNotes(notes string) string {
+	lines := strings.Split(strings.ReplaceAll(notes, "\r\n", "\n"), "\n")
+	for idx, line := range lines {
+		lines[idx] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// SetNotes will replace the notes of the item on the given position. Empty notes remove them.
+func (l *List) SetNotes(pos int, notes string) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	ls[pos-1].Notes = CleanNotes(notes)
+	return nil
+}
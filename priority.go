@@ -0,0 +1,74 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// priorityNames maps the word forms accepted by ParsePriority to their letters
+var priorityNames = map[string]string{
+	"high":   "A",
+	"medium": "B",
+	"low":    "C",
+}
+
+// ParsePriority will convert the priority given by the user into a letter from A to Z. It accepts a letter
+// (case insensitive), a number from 1 to 26 (1 = A) or one of high, medium and low. An empty string or "none"
+// means no priority.
+func ParsePriority(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return "", nil
+	}
+
+	if p, ok := priorityNames[s]; ok {
+		return p, nil
+	}
+
+	// a single letter
+	if len(s) == 1 && s[0] >= 'a' && s[0] <= 'z' {
+		return strings.ToUpper(s), nil
+	}
+
+	// a number, where 1 is the highest priority
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= 26 {
+		return string(rune('A' + n - 1)), nil
+	}
+
+	return "", fmt.Errorf("invalid priority %q", s)
+}
+
+// priorityToken reports whether a word is a priority written the todo.txt way, eg.: (A), and returns it's letter
+func priorityToken(word string) (string, bool) {
+	if len(word) != 3 || word[0] != '(' || word[2] != ')' || word[1] < 'A' || word[1] > 'Z' {
+		return "", false
+	}
+	return word[1:2], true
+}
+
+// priorityRank returns a number used to sort priorities. A sorts first and no priority sorts last.
+func priorityRank(p string) int {
+	if p == "" {
+		return 'Z' + 1
+	}
+	return int(p[0])
+}
+
+// SetPriority will change the priority of the item on the given position. The priority is parsed with ParsePriority.
+func (l *List) SetPriority(pos int, priority string) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	p, err := ParsePriority(priority)
+	if err != nil {
+		return err
+	}
+
+	ls[pos-1].Priority = p
+	return nil
+}
@@ -0,0 +1,246 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//=====================
+// RECURRENCE
+//=====================
+// A recurring item is created again every time it's completed, due on the next date of it's rule. The rule is kept
+// on the item as text, in one of these forms:
+//   - daily
+//   - weekdays                  Monday to Friday
+//   - weekly or weekly:mon,thu  every week on the same weekday, or on the given weekdays
+//   - every:3d or every:2w      every N days or weeks
+//   - monthly or monthly:15     every month on the same day, or on the given day
+// A plain monthly rule is stored with the day it's anchored to: the day of the item's due date, or of it's creation
+// date when it has none. eg.: monthly on an item due January 31st is kept as monthly:31, so it's due on the 31st, or
+// the last day of shorter months, instead of drifting to the 28th after February.
+
+// recurrence is a parsed recurrence rule
+type recurrence struct {
+	kind     string         // daily, weekdays, weekly, every or monthly
+	days     int            // every: the number of days between occurrences
+	weekdays []time.Weekday // weekly: the weekdays, empty for the same weekday
+	monthDay int            // monthly: the day of the month, 0 for the same day
+}
+
+// weekdayNames maps the names accepted in weekly rules to their weekday
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseRecurrence will validate a recurrence rule and return it in it's normalized form. An empty string or
+// "none" means the item doesn't recur.
+func ParseRecurrence(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return "", nil
+	}
+
+	r, err := parseRecurrence(s)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+// parseRecurrence parses a lower case rule into a recurrence
+func parseRecurrence(s string) (recurrence, error) {
+	kind, arg, _ := strings.Cut(s, ":")
+	r := recurrence{kind: kind}
+
+	switch kind {
+	case "daily", "weekdays":
+		if arg != "" {
+			break
+		}
+		return r, nil
+
+	case "weekly":
+		if arg == "" {
+			return r, nil
+		}
+		for _, name := range strings.Split(arg, ",") {
+			// full names are accepted as well. eg.: monday
+			name = strings.TrimSpace(name)
+			wd, ok := weekdayNames[name[:min(3, len(name))]]
+			if !ok || !strings.HasPrefix(strings.ToLower(wd.String()), name) {
+				return r, fmt.Errorf("invalid weekday %q in recurrence %q", name, s)
+			}
+			if !slices.Contains(r.weekdays, wd) {
+				r.weekdays = append(r.weekdays, wd)
+			}
+		}
+		slices.Sort(r.weekdays)
+		return r, nil
+
+	case "every":
+		days, err := parseDays(arg)
+		if err != nil || days <= 0 {
+			break
+		}
+		r.days = days
+		return r, nil
+
+	case "monthly":
+		if arg == "" {
+			return r, nil
+		}
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			break
+		}
+		r.monthDay = day
+		return r, nil
+	}
+
+	return r, fmt.Errorf("invalid recurrence %q. Use daily, weekdays, weekly[:mon,...], every:Nd, every:Nw or monthly[:day]", s)
+}
+
+// String returns the normalized form of the rule
+func (r recurrence) String() string {
+	switch {
+	case r.kind == "weekly" && len(r.weekdays) > 0:
+		names := []string{}
+		for _, wd := range r.weekdays {
+			names = append(names, strings.ToLower(wd.String()[:3]))
+		}
+		return "weekly:" + strings.Join(names, ",")
+	case r.kind == "every":
+		return fmt.Sprintf("every:%dd", r.days)
+	case r.kind == "monthly" && r.monthDay > 0:
+		return fmt.Sprintf("monthly:%d", r.monthDay)
+	}
+	return r.kind
+}
+
+// NextDate returns the first date after t on which an item with the given recurrence rule is due again
+func NextDate(rule string, t time.Time) (time.Time, error) {
+	r, err := parseRecurrence(strings.ToLower(strings.TrimSpace(rule)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return r.next(t), nil
+}
+
+// next returns the first date of the rule after t, keeping the time of day of t
+func (r recurrence) next(t time.Time) time.Time {
+	switch r.kind {
+	case "daily":
+		return t.AddDate(0, 0, 1)
+
+	case "every":
+		return t.AddDate(0, 0, r.days)
+
+	case "weekdays":
+		n := t.AddDate(0, 0, 1)
+		for n.Weekday() == time.Saturday || n.Weekday() == time.Sunday {
+			n = n.AddDate(0, 0, 1)
+		}
+		return n
+
+	case "weekly":
+		if len(r.weekdays) == 0 {
+			return t.AddDate(0, 0, 7)
+		}
+		n := t.AddDate(0, 0, 1)
+		for !slices.Contains(r.weekdays, n.Weekday()) {
+			n = n.AddDate(0, 0, 1)
+		}
+		return n
+
+	case "monthly":
+		day := r.monthDay
+		if day == 0 {
+			day = t.Day()
+		}
+		// the day on this month, if it's still ahead. Otherwise on the next month
+		if n := onMonthDay(t, 0, day); n.After(t) {
+			return n
+		}
+		return onMonthDay(t, 1, day)
+	}
+	return t
+}
+
+// onMonthDay returns the date on the given day of the month, months after t. Days past the end of the month are
+// moved to it's last day. eg.: day 31 in April is April 30th
+func onMonthDay(t time.Time, months, day int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// nextOccurrence returns the item that follows a recurring item once it's completed at now. The next due date is
+// counted from the item's due date, so the schedule doesn't drift, skipping dates that are already past. Items
+// without a due date are counted from now. A recurring subtask stays under the same parent.
+func (i item) nextOccurrence(now time.Time) (item, bool) {
+	if i.Recur == "" {
+		return item{}, false
+	}
+	r, err := parseRecurrence(i.Recur)
+	if err != nil {
+		return item{}, false
+	}
+
+	base := i.Due
+	if base.IsZero() {
+		base = startOfDay(now)
+	}
+	due := r.next(base)
+	for due.Before(startOfDay(now)) {
+		due = r.next(due)
+	}
+
+	return item{
+		Task:      i.Task,
+		CreatedAt: now,
+		Priority:  i.Priority,
+		Due:       due,
+		Tags:      append([]string(nil), i.Tags...),
+		Recur:     i.anchoredRecurrence(i.Recur),
+		Parent:    i.Parent,
+		Notes:     i.Notes,
+	}, true
+}
+
+// SetRecurrence will change the recurrence rule of the item on the given position. The rule is parsed with
+// ParseRecurrence, and a plain monthly rule is anchored to the day of the item's due or creation date.
+func (l *List) SetRecurrence(pos int, rule string) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	r, err := ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+	ls[pos-1].Recur = ls[pos-1].anchoredRecurrence(r)
+	return nil
+}
+
+// anchoredRecurrence returns the normalized rule anchored to the item: a plain monthly rule gets the day of the item's
+// due date, or of it's creation date. The other rules are returned as they are.
+func (i item) anchoredRecurrence(rule string) string {
+	if rule != "monthly" {
+		return rule
+	}
+	anchor := i.Due
+	if anchor.IsZero() {
+		anchor = i.CreatedAt
+	}
+	if anchor.IsZero() {
+		return rule
+	}
+	return fmt.Sprintf("monthly:%d", anchor.Day())
+}
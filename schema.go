@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+//=====================
+// SCHEMA
+//=====================
+// List files are written as a versioned envelope around the items. eg.: {"version": 1, "items": [...]}
+// The version is increased whenever a change to the items would break the files already on disk, along with a
+// migration that upgrades files of the previous version. Files are upgraded when they're read and written in the
+// current version on the next Save. Files of a newer version are refused, so an older binary never drops the fields
+// it doesn't know about.
+// Versions of the schema:
+//   0: a bare JSON array of items, written before the envelope was added. Items written before IDs were added
+//      don't have one.
+//   1: the envelope. Every item has an ID.
+
+// SchemaVersion is the version of the schema List files are written in
+const SchemaVersion = 1
+
+// document is the envelope List files are written in
+type document struct {
+	Version int  `json:"version"`
+	Items   List `json:"items"`
+}
+
+// migration upgrades the contents of a List file from one version of the schema to the next. It works on the raw
+// JSON, so it doesn't depend on the fields item has today.
+type migration func(data []byte) ([]byte, error)
+
+// migrations holds the steps of the schema. migrations[n] upgrades a file of version n to version n+1.
+var migrations = []migration{
+	migrateEnvelope,
+}
+
+// Migrate will upgrade the contents of a List file to SchemaVersion, running every migration from the version
+// the file is in. It returns the upgraded contents and the version the file was in.
+func Migrate(data []byte) ([]byte, int, error) {
+	version, err := schemaVersion(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > SchemaVersion {
+		return nil, version, newerSchemaError(version)
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		if data, err = migrations[v](data); err != nil {
+			return nil, version, fmt.Errorf("upgrading the list from schema version %d: %w", v, err)
+		}
+	}
+	return data, version, nil
+}
+
+// newerSchemaError is returned for lists written with a newer version of the schema than SchemaVersion
+func newerSchemaError(version int) error {
+	return fmt.Errorf("the list was written with schema version %d, newer than this version of todo supports (%d)", version, SchemaVersion)
+}
+
+// schemaVersion detects the version of the schema of a List file. Bare arrays are version 0.
+func schemaVersion(data []byte) (int, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return 0, nil
+	}
+
+	doc := struct {
+		Version int `json:"version"`
+	}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	if doc.Version <= 0 {
+		return 0, fmt.Errorf("the list has no schema version")
+	}
+	return doc.Version, nil
+}
+
+// migrateEnvelope upgrades version 0 to 1: the bare array is wrapped in the envelope and the items saved without an
+// ID get one. The other fields of the items are kept as they are.
+func migrateEnvelope(data []byte) ([]byte, error) {
+	items := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	if items == nil {
+		// a file holding null
+		items = []map[string]json.RawMessage{}
+	}
+
+	// collect the IDs in use, so the new ones don't clash with them
+	ids := make([]string, len(items))
+	used := map[string]bool{}
+	for idx, it := range items {
+		if raw, ok := it["ID"]; ok {
+			if err := json.Unmarshal(raw, &ids[idx]); err != nil {
+				return nil, fmt.Errorf("item %d: %w", idx+1, err)
+			}
+		}
+		used[ids[idx]] = true
+	}
+	for idx, it := range items {
+		if ids[idx] != "" {
+			continue
+		}
+		id := randomID()
+		for used[id] {
+			id = randomID()
+		}
+		used[id] = true
+		it["ID"], _ = json.Marshal(id)
+	}
+
+	return json.Marshal(struct {
+		Version int                          `json:"version"`
+		Items   []map[string]json.RawMessage `json:"items"`
+	}{1, items})
+}
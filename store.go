@@ -0,0 +1,158 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+//=====================
+// STORAGE
+//=====================
+
+// Store is implemented by the backends a List can be persisted to.
+type Store interface {
+	// Load reads the items kept in the store into the List
+	Load(l *List) error
+	// Save persists the whole List to the store
+	Save(l *List) error
+}
+
+// StoreOpener creates a Store backed by the given filename
+type StoreOpener func(filename string) Store
+
+// stores holds the backends available to OpenStore, indexed by their name
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]StoreOpener{
+		"json":    func(filename string) Store { return NewJSONStore(filename) },
+		"journal": func(filename string) Store { return NewJournalStore(filename) },
+	}
+)
+
+// DefaultStore is the name of the backend used when none is specified
+const DefaultStore = "json"
+
+// RegisterStore will make a backend available to OpenStore under the given name. Registering a name twice replaces
+// the previous backend.
+func RegisterStore(name string, open StoreOpener) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = open
+}
+
+// OpenStore will create a Store of the named backend for filename. An empty name selects the DefaultStore.
+func OpenStore(name, filename string) (Store, error) {
+	if name == "" {
+		name = DefaultStore
+	}
+
+	storesMu.RLock()
+	open, ok := stores[name]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store %q", name)
+	}
+	return open(filename), nil
+}
+
+// StoreNames returns the names of every registered backend, sorted
+func StoreNames() []string {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSONStore keeps the whole List as JSON in a single file, wrapped in the versioned envelope of the schema (see
+// SchemaVersion). It's the default backend.
+type JSONStore struct {
+	Filename string
+	// BackupPolicy sets the copies of the file kept before each Save. No copies are kept by default.
+	BackupPolicy BackupPolicy
+}
+
+// NewJSONStore returns a JSONStore backed by filename
+func NewJSONStore(filename string) *JSONStore {
+	return &JSONStore{Filename: filename}
+}
+
+// Save will encode the List as JSON and write it to the store's file
+func (s *JSONStore) Save(l *List) error {
+	// marshal the List into json format, inside the envelope. An empty List is written as an empty array
+	items := *l
+	if items == nil {
+		items = List{}
+	}
+	js, err := json.Marshal(document{Version: SchemaVersion, Items: items})
+	if err != nil {
+		return err
+	}
+
+	// nothing to do when the file already holds the same List. Skipping the save also keeps an unchanged List from
+	// taking a backup, which would otherwise rotate out an older version that's different
+	current, err := os.ReadFile(s.Filename)
+	switch {
+	case err == nil && bytes.Equal(current, js):
+		return nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	// keep a copy of the file about to be replaced
+	if err := s.backup(time.Now(), current); err != nil {
+		return fmt.Errorf("backing up %s: %w", s.Filename, err)
+	}
+
+	// write to the file system. writeFile replaces the file atomically, so a crash never leaves a truncated file behind
+	return writeFile(s.Filename, js)
+}
+
+// Load will open the store's file and decode the json into the List slice
+func (s *JSONStore) Load(l *List) error {
+
+	// try read the file from the os.
+	file, err := os.ReadFile(s.Filename)
+	if err != nil {
+		switch {
+		// file didn't exist. Function returns nil to the caller.
+		case errors.Is(err, os.ErrNotExist):
+			return nil
+			// some other unknown error
+		default:
+			return err
+		}
+	}
+
+	// check wether the file is empty
+	if len(file) == 0 {
+		return nil
+	}
+
+	// upgrade files written in an older version of the schema. They're written in the current one on the next Save
+	file, _, err = Migrate(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.Filename, err)
+	}
+
+	// file read. // Unmarshal from JSON into the List slice.
+	doc := document{}
+	if err := json.Unmarshal(file, &doc); err != nil {
+		return fmt.Errorf("%s: %w", s.Filename, err)
+	}
+	*l = doc.Items
+
+	// give an ID to any item that was saved without one (eg.: added to the file by hand)
+	l.ensureIDs()
+	return nil
+}
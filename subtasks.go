@@ -0,0 +1,153 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//=====================
+// SUBTASKS
+//=====================
+// Items can have subtasks. The List is still a flat slice: a subtask keeps the ID of it's parent in the Parent field,
+// and positions keep counting every item in the order they're stored. Views show the items as a tree instead,
+// numbered by their place on it: 2.1 is the first subtask of the second top-level item. Resolve accepts those
+// numbers, so they can be used to refer to the items on the command line.
+// A task can't be completed while it has open subtasks, and completing the last open subtask completes it's parent.
+// Deleting a task deletes it's subtasks as well.
+
+// AddSubtask will add a new item to the end of the List as a subtask of the item on the given position
+func (l *List) AddSubtask(parent int, taskName string) error {
+	// check whether the position passed is valid
+	if parent <= 0 || parent > len(*l) {
+		return fmt.Errorf("item %d does not exist", parent)
+	}
+	if (*l)[parent-1].Done {
+		return fmt.Errorf("%q is completed. Reopen it to add subtasks", (*l)[parent-1].Task)
+	}
+
+	l.Add(taskName)
+	(*l)[len(*l)-1].Parent = (*l)[parent-1].ID
+	return nil
+}
+
+// SetParent will move the item on the given position (along with it's subtasks) under the item on the parent
+// position. A parent of 0 makes it a top-level item. An item can't be moved under itself or one of it's own
+// subtasks, and an open item can't be moved under a completed one.
+func (l *List) SetParent(pos, parent int) error {
+	ls := *l
+
+	// check whether the positions passed are valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+	if parent == 0 {
+		ls[pos-1].Parent = ""
+		return nil
+	}
+	if parent < 0 || parent > len(ls) {
+		return fmt.Errorf("item %d does not exist", parent)
+	}
+
+	if parent == pos || l.isDescendant(parent, pos) {
+		return fmt.Errorf("%q can't be a subtask of itself", ls[pos-1].Task)
+	}
+	if ls[parent-1].Done && !ls[pos-1].Done {
+		return fmt.Errorf("%q is completed. Reopen it to add subtasks", ls[parent-1].Task)
+	}
+
+	ls[pos-1].Parent = ls[parent-1].ID
+	return nil
+}
+
+// children returns the positions of the direct subtasks of the item on the given position
+func (l *List) children(pos int) []int {
+	id := (*l)[pos-1].ID
+	positions := []int{}
+	for idx, it := range *l {
+		if it.Parent == id && idx+1 != pos {
+			positions = append(positions, idx+1)
+		}
+	}
+	return positions
+}
+
+// descendants returns the positions of the subtasks of the item on the given position, their subtasks and so on
+func (l *List) descendants(pos int) []int {
+	seen := map[int]bool{pos: true}
+	found := []int{}
+
+	queue := []int{pos}
+	for len(queue) > 0 {
+		for _, c := range l.children(queue[0]) {
+			// a List edited by hand may have a loop of parents. Don't go around it
+			if !seen[c] {
+				seen[c] = true
+				found = append(found, c)
+				queue = append(queue, c)
+			}
+		}
+		queue = queue[1:]
+	}
+	return found
+}
+
+// isDescendant reports whether the item on pos is a subtask (direct or not) of the item on the ancestor position
+func (l *List) isDescendant(pos, ancestor int) bool {
+	for _, d := range l.descendants(ancestor) {
+		if d == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// openSubtasks returns the number of subtasks of the item on the given position that aren't completed yet
+func (l *List) openSubtasks(pos int) int {
+	open := 0
+	for _, d := range l.descendants(pos) {
+		if !(*l)[d-1].Done {
+			open++
+		}
+	}
+	return open
+}
+
+// parentPosition returns the position of the parent of the item on the given position, or 0 for top-level items and
+// items whose parent no longer exists
+func (l *List) parentPosition(pos int) int {
+	parent := (*l)[pos-1].Parent
+	if parent == "" {
+		return 0
+	}
+	ppos, err := l.Position(parent)
+	if err != nil || ppos == pos {
+		return 0
+	}
+	return ppos
+}
+
+// depth returns how many parents the item on the given position has
+func (l *List) depth(pos int) int {
+	d := 0
+	seen := map[int]bool{pos: true}
+	for p := l.parentPosition(pos); p != 0 && !seen[p]; p = l.parentPosition(p) {
+		seen[p] = true
+		d++
+	}
+	return d
+}
+
+// treeNumber parses a number on the tree, eg.: "2" or "2.1", into it's canonical form. ok is false when ref isn't a
+// tree number.
+func treeNumber(ref string) (string, bool) {
+	parts := strings.Split(ref, ".")
+	for idx, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return "", false
+		}
+		parts[idx] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, "."), true
+}
@@ -0,0 +1,111 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//=====================
+// TAGS
+//=====================
+// Tags group items across the List. The todo.txt conventions are used: +project and @context, but plain tags
+// (eg.: "urgent") are allowed as well. Tags are kept apart from the task text and displayed after it.
+
+// isTagToken reports whether a word of a task is a +project or @context tag
+func isTagToken(word string) bool {
+	return len(word) > 1 && (word[0] == '+' || word[0] == '@')
+}
+
+// ParseTags will split the +project and @context tokens off a task's text. It returns the text without them and
+// the tags found, in the order they appear.
+func ParseTags(text string) (string, []string) {
+	words := []string{}
+	tags := []string{}
+	for _, word := range strings.Fields(text) {
+		if isTagToken(word) {
+			tags = append(tags, word)
+			continue
+		}
+		words = append(words, word)
+	}
+
+	// keep the text untouched when there are no tags, so spacing isn't changed
+	if len(tags) == 0 {
+		return text, nil
+	}
+	return strings.Join(words, " "), tags
+}
+
+// hasTag reports whether the item has the tag, ignoring case
+func (i item) hasTag(tag string) bool {
+	return slices.ContainsFunc(i.Tags, func(t string) bool {
+		return strings.EqualFold(t, tag)
+	})
+}
+
+// text returns the task followed by it's tags, as it's displayed
+func (i item) text() string {
+	if len(i.Tags) == 0 {
+		return i.Task
+	}
+	return i.Task + " " + strings.Join(i.Tags, " ")
+}
+
+// AddTags will add the tags to the item on the given position. Tags the item already has are skipped.
+func (l *List) AddTags(pos int, tags ...string) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || ls[pos-1].hasTag(tag) {
+			continue
+		}
+		// INFO: build a new slice, so copies of the item (eg.: in a View) aren't changed
+		ls[pos-1].Tags = append(slices.Clip(ls[pos-1].Tags), tag)
+	}
+	return nil
+}
+
+// RemoveTags will remove the tags from the item on the given position, ignoring case
+func (l *List) RemoveTags(pos int, tags ...string) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+
+	kept := []string{}
+	for _, t := range ls[pos-1].Tags {
+		remove := slices.ContainsFunc(tags, func(tag string) bool {
+			return strings.EqualFold(t, strings.TrimSpace(tag))
+		})
+		if !remove {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	ls[pos-1].Tags = kept
+	return nil
+}
+
+// Tags returns every tag used on the List, in the order they first appear
+func (l *List) Tags() []string {
+	tags := []string{}
+	for _, it := range *l {
+		for _, tag := range it.Tags {
+			if !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
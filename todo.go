@@ -1,11 +1,9 @@
 package todo
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/fs"
-	"os"
+	"math/rand/v2"
+	"slices"
 	"time"
 )
 
@@ -14,31 +12,52 @@ import (
 //=====================
 // 'item' will be used internally by the 'todo' package.
 // will hold fields representing a information about a particular 'ToDo' item.
+// ID is a persistent identifier that doesn't change when other items are deleted or the List is reloaded.
+// Priority is a single letter from A (highest) to Z, or empty when the item has no priority.
+// Due is the optional due date. It's the zero time when the item has none.
+// Tags holds the +project, @context and plain tags of the item.
+// ModifiedAt is the last time the item was changed with Edit. It's the zero time when it was never edited.
+// Reopened keeps the completions that were undone by Reopen, oldest first.
+// Recur is the recurrence rule of the item (see ParseRecurrence), or empty when it doesn't repeat.
+// Next is the ID of the occurrence created when the recurring item was completed, so completing it again after a
+// Reopen doesn't create another one.
+// Parent is the ID of the item this one is a subtask of, or empty for top-level items.
+// BlockedBy holds the IDs of the items that have to be done before this one (see Block).
+// Notes is an optional multi-line description of the item.
+// Extra keeps the key:value pairs of a todo.txt line that don't map onto any other field (see ImportTodoTxt).
 
 type item struct {
+	ID          string
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Priority    string `json:",omitempty"`
+	Due         time.Time
+	Tags        []string `json:",omitempty"`
+	ModifiedAt  time.Time
+	Reopened    []Reopening `json:",omitempty"`
+	Recur       string      `json:",omitempty"`
+	Next        string      `json:",omitempty"`
+	Parent      string      `json:",omitempty"`
+	BlockedBy   []string    `json:",omitempty"`
+	Notes       string      `json:",omitempty"`
+	Extra       []string    `json:",omitempty"`
+}
+
+// Reopening records a completed item being reopened: when it had been completed and when it was reopened
+type Reopening struct {
+	CompletedAt time.Time
+	ReopenedAt  time.Time
 }
 
 // List represents a list of Todo items
 type List []item
 
 // String method will provide the List a method to return a formated string
+// INFO: the formatting is done by the View, so a filtered or sorted View prints the same way.
 func (l *List) String() string {
-	formated := ""
-	// add a prefix to be displayed
-	for idx, item := range *l {
-		prefix := "[ ] "
-		if item.Done {
-			prefix = "[x] "
-		}
-		// update the format. will dispaly the prefix an order number and a Task name
-		// eg.: [] 1 Buy Stuff, [x] 2 Go Out
-		formated += fmt.Sprintf("%s%d: %s\n", prefix, idx+1, item.Task)
-	}
-	return formated
+	return l.View().String()
 }
 
 // Add will add a new ToDo element to the List slice
@@ -46,6 +65,7 @@ func (l *List) Add(taskName string) {
 
 	// instantiate a new ToDo item using a struct litetral and the task name provided.
 	td := item{
+		ID:          l.newID(),
 		Task:        taskName,
 		Done:        false,
 		CreatedAt:   time.Now(),
@@ -58,8 +78,9 @@ func (l *List) Add(taskName string) {
 }
 
 // Complete method will mark a ToDo as completed by flipping the Done field and adding a CompletedAt time
-// INFO: the method won't modifying the List 'l' directly.. So using a pointer receiver is just to maintain conistency
-// with the other methods.
+// INFO: the method only modifies the List 'l' directly when the item recurs: completing a recurring item adds it's
+// next occurrence to the end of the List, due on the next date of it's rule.
+// A ToDo with open subtasks can't be completed. Completing the last open subtask of a ToDo completes it as well.
 func (l *List) Complete(pos int) error {
 
 	// store the dereferenced value of the List l to perform a len check
@@ -71,15 +92,62 @@ func (l *List) Complete(pos int) error {
 		return fmt.Errorf("item %d does not exist", pos)
 	}
 
+	wasDone := ls[pos-1].Done
+	if open := l.openSubtasks(pos); open > 0 && !wasDone {
+		return fmt.Errorf("%q has %d open subtask(s)", ls[pos-1].Task, open)
+	}
+
 	// Update the ToDo's Done and CompleteAt fields
 	// Backing Array is the same for ls and l. ls is modifying the backing array, l will change as well.
+	now := time.Now()
 	ls[pos-1].Done = true
-	ls[pos-1].CompletedAt = time.Now()
+	ls[pos-1].CompletedAt = now
+
+	// create the next occurrence of a recurring item. Completing it again doesn't create another one, and neither
+	// does completing it after a Reopen, as long as the occurrence created before is still on the List.
+	if next, ok := ls[pos-1].nextOccurrence(now); ok && !wasDone && !l.hasID(ls[pos-1].Next) {
+		next.ID = l.newID()
+		ls[pos-1].Next = next.ID
+		*l = append(*l, next)
+	}
+
+	// complete the parent once all it's subtasks are done
+	if parent := l.parentPosition(pos); parent != 0 && !(*l)[parent-1].Done && l.openSubtasks(parent) == 0 {
+		return l.Complete(parent)
+	}
 
 	return nil
 }
 
-// Delete will remove an Todo item from the List
+// Reopen will mark a completed ToDo as active again, clearing the Done and CompletedAt fields. The completion
+// being undone is kept in the item's Reopened field. Reopening a subtask reopens it's completed parents as well.
+func (l *List) Reopen(pos int) error {
+	ls := *l
+
+	// check whether the position passed is valid
+	if pos <= 0 || pos > len(ls) {
+		return fmt.Errorf("item %d does not exist", pos)
+	}
+	if !ls[pos-1].Done {
+		return fmt.Errorf("item %d is not completed", pos)
+	}
+
+	// keep the audit of the completion. Build a new slice, so copies of the item aren't changed
+	reopening := Reopening{CompletedAt: ls[pos-1].CompletedAt, ReopenedAt: time.Now()}
+	ls[pos-1].Reopened = append(slices.Clip(ls[pos-1].Reopened), reopening)
+
+	ls[pos-1].Done = false
+	ls[pos-1].CompletedAt = time.Time{}
+
+	// a completed parent can't have open subtasks
+	if parent := l.parentPosition(pos); parent != 0 && ls[parent-1].Done {
+		return l.Reopen(parent)
+	}
+
+	return nil
+}
+
+// Delete will remove an Todo item from the List, along with all it's subtasks
 func (l *List) Delete(pos int) error {
 	// store the dereferenced value of the List l to perform a len check
 	// both ls and l have the same Backing Array
@@ -90,6 +158,22 @@ func (l *List) Delete(pos int) error {
 		return fmt.Errorf("item %d does not exist", pos)
 	}
 
+	// a ToDo with subtasks is removed by keeping every item that isn't part of it's tree
+	if subtasks := l.descendants(pos); len(subtasks) > 0 {
+		removed := map[int]bool{pos: true}
+		for _, s := range subtasks {
+			removed[s] = true
+		}
+		kept := List{}
+		for idx, it := range ls {
+			if !removed[idx+1] {
+				kept = append(kept, it)
+			}
+		}
+		*l = kept
+		return nil
+	}
+
 	// remove the ToDo from the slice by slicing off the index of the passed position (position starts at 1, while index starts at 0)
 	left := ls[:pos-1] // first half of the slice, without the element we need to cut
 	right := ls[pos:]  // second half of the slice
@@ -101,39 +185,121 @@ func (l *List) Delete(pos int) error {
 	return nil
 }
 
-// Save method will encode the List as JSON and save it using the provided filename
-func (l *List) Save(filename string) error {
-	// marshal the List into json format
-	js, err := json.Marshal(l)
-	if err != nil {
-		return err
+// newID generates a random 8 character hex identifier for a new item. It'll keep generating until it finds one
+// that isn't already used by another item on the List.
+func (l *List) newID() string {
+	for {
+		id := randomID()
+		if _, err := l.Position(id); err != nil {
+			return id
+		}
 	}
+}
 
-	// write to the file system
-	return os.WriteFile(filename, js, fs.FileMode(os.O_WRONLY))
+// randomID generates a random 8 character hex identifier
+func randomID() string {
+	return fmt.Sprintf("%08x", rand.Uint32())
 }
 
-// Get method will open the file and decode the json file into the List slice
-func (l *List) Get(filename string) error {
+// ensureIDs will assign a new ID to every item that doesn't have one yet. (eg.: items loaded from a file saved by
+// an older version of the tool)
+func (l *List) ensureIDs() {
+	ls := *l
+	for idx := range ls {
+		if ls[idx].ID == "" {
+			ls[idx].ID = l.newID()
+		}
+	}
+}
 
-	// try read the file from the os.
-	file, err := os.ReadFile(filename)
-	if err != nil {
-		switch {
-		// file didn't exist. Function returns nil to the caller.
-		case errors.Is(err, os.ErrNotExist):
-			return nil
-			// some other unknown error
-		default:
-			return err
+// Position will look up an item by it's ID and return it's current position (starting at 1) on the List
+func (l *List) Position(id string) (int, error) {
+	for idx, item := range *l {
+		if item.ID == id {
+			return idx + 1, nil
 		}
 	}
+	return 0, fmt.Errorf("item with ID %q does not exist", id)
+}
 
-	// check wether the file is empty
-	if len(file) == 0 {
-		return nil
+// hasID reports whether an item with the given ID is on the List
+func (l List) hasID(id string) bool {
+	if id == "" {
+		return false
+	}
+	_, err := l.Position(id)
+	return err == nil
+}
+
+// indexByID maps the ID of every item to it's index on the List, so many items can be looked up without scanning the
+// List for each one. Like Position, the first item wins when an ID is repeated.
+func (l List) indexByID() map[string]int {
+	index := make(map[string]int, len(l))
+	for idx, it := range l {
+		if _, ok := index[it.ID]; !ok {
+			index[it.ID] = idx
+		}
+	}
+	return index
+}
+
+// Resolve will translate a reference to an item into it's position on the List. The reference can either be the
+// item's ID or it's number as displayed. eg.: 3, or 2.1 for a subtask. IDs are checked first, so a reference is only
+// treated as a number when no ID matches. On a List without subtasks the numbers are the positions.
+func (l *List) Resolve(ref string) (int, error) {
+	if pos, err := l.Position(ref); err == nil {
+		return pos, nil
+	}
+
+	// not an ID. try to parse it as a number on the tree
+	num, ok := treeNumber(ref)
+	if !ok {
+		return 0, fmt.Errorf("item %q does not exist", ref)
+	}
+	for _, e := range l.View() {
+		if e.Num == num {
+			return e.Pos, nil
+		}
+	}
+	return 0, fmt.Errorf("item %s does not exist", num)
+}
+
+// CompleteID will mark the item with the given ID as completed
+func (l *List) CompleteID(id string) error {
+	pos, err := l.Position(id)
+	if err != nil {
+		return err
+	}
+	return l.Complete(pos)
+}
+
+// ReopenID will mark the completed item with the given ID as active again
+func (l *List) ReopenID(id string) error {
+	pos, err := l.Position(id)
+	if err != nil {
+		return err
+	}
+	return l.Reopen(pos)
+}
+
+// DeleteID will remove the item with the given ID from the List
+func (l *List) DeleteID(id string) error {
+	pos, err := l.Position(id)
+	if err != nil {
+		return err
 	}
+	return l.Delete(pos)
+}
+
+// Save method will encode the List as JSON and save it using the provided filename
+// INFO: this is a shortcut for saving with the default JSON file Store.
+func (l *List) Save(filename string) error {
+	return NewJSONStore(filename).Save(l)
+}
 
-	// file read. // Unmarshal from JSON into the List slice.
-	return json.Unmarshal(file, &l)
+// Get method will open the file and decode the json file into the List slice
+// INFO: this is a shortcut for loading from the default JSON file Store. Files written in an older version of the
+// schema (eg.: a bare JSON array) are upgraded as they're read (see Migrate).
+func (l *List) Get(filename string) error {
+	return NewJSONStore(filename).Load(l)
 }
@@ -6,7 +6,10 @@ package todo_test
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dupakarovsky/todo"
 )
@@ -141,3 +144,1297 @@ func TestSaveGet(t *testing.T) {
 	}
 
 }
+
+// TestIDs will check that every item receives a unique ID on Add and that the ID keeps pointing to the same item
+// after another item is deleted and the positions shift.
+func TestIDs(t *testing.T) {
+	var l todo.List
+
+	tasks := []string{"Task 1", "Task 2", "Task 3"}
+	for _, task := range tasks {
+		l.Add(task)
+	}
+
+	// IDs should be set and unique
+	if l[0].ID == "" || l[0].ID == l[1].ID || l[1].ID == l[2].ID {
+		t.Fatalf("expected unique IDs; got %q, %q, %q", l[0].ID, l[1].ID, l[2].ID)
+	}
+
+	// store the ID of the 3rd task and delete the 2nd one by it's ID.
+	id := l[2].ID
+	if err := l.DeleteID(l[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the 3rd task moved to position 2, but it's ID should still resolve to it
+	pos, err := l.Position(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 2 {
+		t.Errorf("expected position %d; got %d instead", 2, pos)
+	}
+
+	// complete it using the ID
+	if err := l.CompleteID(id); err != nil {
+		t.Fatal(err)
+	}
+	if !l[1].Done {
+		t.Errorf("expected %q to be completed", l[1].Task)
+	}
+
+	// Resolve should accept either an ID or a position
+	if pos, err := l.Resolve("1"); err != nil || pos != 1 {
+		t.Errorf("expected position 1; got %d (%v)", pos, err)
+	}
+	if _, err := l.Resolve("nope"); err == nil {
+		t.Errorf("expected an error resolving an unknown reference")
+	}
+}
+
+// TestGetAssignsIDs will check that items saved without an ID (older todo files) are given one by Get()
+func TestGetAssignsIDs(t *testing.T) {
+	temp, err := os.CreateTemp("", "tempfile_")
+	if err != nil {
+		t.Fatalf("Error creating temp file : %s", err.Error())
+	}
+	defer os.Remove(temp.Name())
+
+	// write a legacy file with no ID field
+	legacy := `[{"Task":"Old Task","Done":false,"CreatedAt":"2024-01-01T00:00:00Z","CompletedAt":"0001-01-01T00:00:00Z"}]`
+	if err := os.WriteFile(temp.Name(), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var l todo.List
+	if err := l.Get(temp.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].ID == "" {
+		t.Errorf("expected the legacy item to receive an ID")
+	}
+}
+
+// TestSavePermissions will check that Save creates readable files, keeps the permissions of an existing file and
+// doesn't leave any temporary file behind
+func TestSavePermissions(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	var l todo.List
+	l.Add("New Task")
+
+	// a new file should be created with 0644
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("expected mode %v; got %v instead", os.FileMode(0644), info.Mode().Perm())
+	}
+
+	// an existing file should keep it's permissions
+	if err := os.Chmod(filename, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode %v; got %v instead", os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// only the List file should be in the directory
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected 1 file in %s; got %d instead", dir, len(entries))
+	}
+}
+
+// memStore is a Store that keeps the List in memory. Used to test plugging in a custom backend.
+type memStore struct {
+	saved todo.List
+}
+
+func (m *memStore) Load(l *todo.List) error {
+	*l = append(todo.List{}, m.saved...)
+	return nil
+}
+
+func (m *memStore) Save(l *todo.List) error {
+	m.saved = append(todo.List{}, *l...)
+	return nil
+}
+
+// TestOpenStore will check the default JSON backend and a custom registered backend can be opened by name
+func TestOpenStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	// the empty name selects the default JSON store
+	s, err := todo.OpenStore("", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*todo.JSONStore); !ok {
+		t.Errorf("expected a *todo.JSONStore; got %T instead", s)
+	}
+
+	// unknown backends return an error
+	if _, err := todo.OpenStore("nope", filename); err == nil {
+		t.Errorf("expected an error opening an unknown store")
+	}
+
+	// register the in memory store and round trip a List through it
+	mem := &memStore{}
+	todo.RegisterStore("mem", func(string) todo.Store { return mem })
+	s, err = todo.OpenStore("mem", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l1, l2 todo.List
+	l1.Add("New Task")
+	if err := s.Save(&l1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(&l2); err != nil {
+		t.Fatal(err)
+	}
+	if len(l2) != 1 || l2[0].ID != l1[0].ID {
+		t.Errorf("expected %v; got %v instead", l1, l2)
+	}
+}
+
+// TestPriority will parse priorities, set them on items and check the List is displayed and sorted by them
+func TestPriority(t *testing.T) {
+	// valid and invalid inputs for ParsePriority
+	cases := map[string]string{"a": "A", "Z": "Z", "1": "A", "3": "C", "high": "A", "Low": "C", "": "", "none": ""}
+	for in, exp := range cases {
+		got, err := todo.ParsePriority(in)
+		if err != nil {
+			t.Errorf("ParsePriority(%q): %v", in, err)
+		}
+		if got != exp {
+			t.Errorf("ParsePriority(%q): expected %q; got %q instead", in, exp, got)
+		}
+	}
+	for _, in := range []string{"AB", "0", "27", "urgent"} {
+		if _, err := todo.ParsePriority(in); err == nil {
+			t.Errorf("ParsePriority(%q): expected an error", in)
+		}
+	}
+
+	var l todo.List
+	for _, task := range []string{"Task 1", "Task 2", "Task 3"} {
+		l.Add(task)
+	}
+	if err := l.SetPriority(2, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPriority(3, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPriority(4, "a"); err == nil {
+		t.Errorf("expected an error setting the priority of a missing item")
+	}
+
+	// the priority is shown before the task
+	exp := "[ ] 1: Task 1\n[ ] 2: (C) Task 2\n[ ] 3: (A) Task 3\n"
+	if l.String() != exp {
+		t.Errorf("expected %q; got %q instead", exp, l.String())
+	}
+
+	// sorted by priority, items keep their positions
+	exp = "[ ] 3: (A) Task 3\n[ ] 2: (C) Task 2\n[ ] 1: Task 1\n"
+	if got := l.View().SortByPriority().String(); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+}
+
+// TestDue will parse due dates in their different forms and check the overdue and upcoming views
+func TestDue(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"":                     {},
+		"today":                today,
+		"Tomorrow":             today.AddDate(0, 0, 1),
+		"+3d":                  today.AddDate(0, 0, 3),
+		"+2w":                  today.AddDate(0, 0, 14),
+		"-1w":                  today.AddDate(0, 0, -7),
+		"yesterday":            today.AddDate(0, 0, -1),
+		"2026-11-01":           time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		"2026-11-01 09:15":     time.Date(2026, 11, 1, 9, 15, 0, 0, time.UTC),
+		"2026-11-01T09:15:00Z": time.Date(2026, 11, 1, 9, 15, 0, 0, time.UTC),
+	}
+	for in, exp := range cases {
+		got, err := todo.ParseDate(in, now)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", in, err)
+			continue
+		}
+		if !got.Equal(exp) {
+			t.Errorf("ParseDate(%q): expected %v; got %v instead", in, exp, got)
+		}
+	}
+	for _, in := range []string{"soon", "+3", "+xd", "2026-13-01"} {
+		if _, err := todo.ParseDate(in, now); err == nil {
+			t.Errorf("ParseDate(%q): expected an error", in)
+		}
+	}
+
+	var l todo.List
+	for _, task := range []string{"Yesterday", "Today", "Next week", "Next month", "No due"} {
+		l.Add(task)
+	}
+	l.SetDue(1, today.AddDate(0, 0, -1))
+	l.SetDue(2, today)
+	l.SetDue(3, today.AddDate(0, 0, 6))
+	l.SetDue(4, today.AddDate(0, 1, 0))
+
+	// tasks due today aren't overdue until the day is over
+	exp := "[ ] 1: Yesterday\n"
+	if got := l.View().Overdue(now).String(); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+
+	exp = "[ ] 2: Today\n[ ] 3: Next week\n"
+	if got := l.View().DueWithin(now, 7*24*time.Hour).String(); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+
+	// done tasks are never overdue
+	l.Complete(1)
+	if got := l.View().Overdue(now); len(got) != 0 {
+		t.Errorf("expected no overdue tasks; got %q", got.String())
+	}
+}
+
+// TestFilter will combine the conditions of a Filter and check the matching items keep their positions
+func TestFilter(t *testing.T) {
+	var l todo.List
+	for _, task := range []string{"Buy milk", "Write report", "Buy bread", "Call mom"} {
+		l.Add(task)
+	}
+	l.SetPriority(1, "A")
+	l.SetPriority(3, "C")
+	l.Complete(3)
+	l.Complete(4)
+
+	now := time.Now()
+	cases := []struct {
+		name   string
+		filter todo.Filter
+		exp    string
+	}{
+		{"all", todo.Filter{}, l.String()},
+		{"active", todo.Filter{Status: todo.StatusActive}, "[ ] 1: (A) Buy milk\n[ ] 2: Write report\n"},
+		{"done and text", todo.Filter{Status: todo.StatusDone, Text: "BUY"}, "[x] 3: (C) Buy bread\n"},
+		{"no priority", todo.Filter{Priorities: []string{""}}, "[ ] 2: Write report\n[x] 4: Call mom\n"},
+		{"created in range", todo.Filter{CreatedAfter: now.Add(-time.Hour), CreatedBefore: now.Add(time.Hour), Text: "mom"}, "[x] 4: Call mom\n"},
+		{"created later", todo.Filter{CreatedAfter: now.Add(time.Hour)}, ""},
+		{"completed", todo.Filter{CompletedAfter: now.Add(-time.Hour)}, "[x] 3: (C) Buy bread\n[x] 4: Call mom\n"},
+	}
+	for _, tc := range cases {
+		if got := l.Filter(tc.filter).String(); got != tc.exp {
+			t.Errorf("%s: expected %q; got %q instead", tc.name, tc.exp, got)
+		}
+	}
+
+	// priority lists and ranges
+	p, err := todo.ParsePriorities("a-c,none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"A", "B", "C", ""}; fmt.Sprint(p) != fmt.Sprint(exp) {
+		t.Errorf("expected %q; got %q instead", exp, p)
+	}
+	if _, err := todo.ParsePriorities("C-A"); err == nil {
+		t.Errorf("expected an error parsing a reversed range")
+	}
+}
+
+// TestTags will parse tags off task texts, change the tags of items and filter by them
+func TestTags(t *testing.T) {
+	task, tags := todo.ParseTags("Fix the +website login bug @office")
+	if task != "Fix the login bug" {
+		t.Errorf("expected %q; got %q instead", "Fix the login bug", task)
+	}
+	if fmt.Sprint(tags) != "[+website @office]" {
+		t.Errorf("expected %v; got %v instead", "[+website @office]", tags)
+	}
+
+	// a lonely + or @ isn't a tag
+	if task, tags := todo.ParseTags("C + C++ @"); task != "C + C++ @" || tags != nil {
+		t.Errorf("expected no tags; got %q %v", task, tags)
+	}
+
+	var l todo.List
+	l.Add("Fix the login bug")
+	l.Add("Water plants")
+	l.AddTags(1, tags...)
+	l.AddTags(1, "+Website", "urgent")
+	l.AddTags(2, "@home")
+
+	// tags are displayed after the task. +Website was skipped as +website is already there
+	exp := "[ ] 1: Fix the login bug +website @office urgent\n[ ] 2: Water plants @home\n"
+	if l.String() != exp {
+		t.Errorf("expected %q; got %q instead", exp, l.String())
+	}
+
+	if got := l.Filter(todo.Filter{Tags: []string{"+WEBSITE", "urgent"}}).String(); got != "[ ] 1: Fix the login bug +website @office urgent\n" {
+		t.Errorf("unexpected filter result %q", got)
+	}
+
+	l.RemoveTags(1, "urgent", "@office")
+	if fmt.Sprint(l[0].Tags) != "[+website]" {
+		t.Errorf("expected %v; got %v instead", "[+website]", l[0].Tags)
+	}
+	if fmt.Sprint(l.Tags()) != "[+website @home]" {
+		t.Errorf("expected %v; got %v instead", "[+website @home]", l.Tags())
+	}
+}
+
+// TestEdit will rename an item and change it's metadata, checking it keeps it's ID, position and creation date
+func TestEdit(t *testing.T) {
+	var l todo.List
+	l.Add("Task 1")
+	l.Add("Tsak 2")
+	orig := l[1]
+
+	// a change that doesn't change anything isn't recorded
+	same := "Tsak 2"
+	if err := l.Edit(2, todo.Changes{Task: &same}); err != nil {
+		t.Fatal(err)
+	}
+	if !l[1].ModifiedAt.IsZero() {
+		t.Errorf("expected ModifiedAt to be unset")
+	}
+
+	task := "Task 2"
+	priority := "B"
+	if err := l.Edit(2, todo.Changes{Task: &task, Priority: &priority, AddTags: []string{"+fix"}}); err != nil {
+		t.Fatal(err)
+	}
+	if l[1].Task != task || l[1].Priority != "B" || fmt.Sprint(l[1].Tags) != "[+fix]" {
+		t.Errorf("unexpected item after edit: %+v", l[1])
+	}
+	if l[1].ID != orig.ID || !l[1].CreatedAt.Equal(orig.CreatedAt) {
+		t.Errorf("expected ID and CreatedAt to be kept")
+	}
+	if l[1].ModifiedAt.IsZero() {
+		t.Errorf("expected ModifiedAt to be set")
+	}
+
+	// an invalid change leaves the item untouched
+	blank := " "
+	bad := "urgent"
+	if err := l.Edit(2, todo.Changes{Task: &blank}); err == nil {
+		t.Errorf("expected an error for a blank task")
+	}
+	if err := l.Edit(2, todo.Changes{Task: &same, Priority: &bad}); err == nil {
+		t.Errorf("expected an error for an invalid priority")
+	}
+	if l[1].Task != task {
+		t.Errorf("expected %q; got %q instead", task, l[1].Task)
+	}
+	if err := l.Edit(3, todo.Changes{Task: &task}); err == nil {
+		t.Errorf("expected an error editing a missing item")
+	}
+}
+
+// TestHistory will record the changes of several commands, undo them back to the start and redo them
+func TestHistory(t *testing.T) {
+	h := todo.NewHistory()
+	var l todo.List
+
+	// each function is a command changing the List
+	commands := []func(){
+		func() { l.Add("Task 1"); l.Add("Task 2"); l.Add("Task 3") },
+		func() { l.Complete(1) },
+		func() { task := "Task 3 edited"; l.Edit(3, todo.Changes{Task: &task}) },
+		func() { l.Delete(2) },
+	}
+
+	// keep the String() of the List after each command
+	states := []string{l.String()}
+	for idx, cmd := range commands {
+		before := l.Clone()
+		cmd()
+		h.Record(fmt.Sprintf("cmd %d", idx), before, l)
+		states = append(states, l.String())
+	}
+
+	// undo every step. The List should go through the same states backwards
+	for i := len(commands) - 1; i >= 0; i-- {
+		if _, err := h.Undo(&l); err != nil {
+			t.Fatal(err)
+		}
+		if l.String() != states[i] {
+			t.Errorf("undo %d: expected %q; got %q instead", i, states[i], l.String())
+		}
+	}
+	if _, err := h.Undo(&l); err == nil {
+		t.Errorf("expected an error with nothing to undo")
+	}
+
+	// and redo them
+	for i := 1; i <= len(commands); i++ {
+		if _, err := h.Redo(&l); err != nil {
+			t.Fatal(err)
+		}
+		if l.String() != states[i] {
+			t.Errorf("redo %d: expected %q; got %q instead", i, states[i], l.String())
+		}
+	}
+
+	// a new change discards the redo steps
+	h.Undo(&l)
+	before := l.Clone()
+	l.Add("Task 4")
+	h.Record("add", before, l)
+	if _, err := h.Redo(&l); err == nil {
+		t.Errorf("expected an error with nothing to redo")
+	}
+
+	// the history keeps up to Max steps
+	h.Max = 2
+	before = l.Clone()
+	l.Add("Task 5")
+	h.Record("add", before, l)
+	if len(h.Steps) != 2 {
+		t.Errorf("expected %d steps; got %d instead", 2, len(h.Steps))
+	}
+}
+
+// TestReopen will complete an item, reopen it and check the previous completion is kept
+func TestReopen(t *testing.T) {
+	var l todo.List
+	l.Add("Task 1")
+
+	// an active item can't be reopened
+	if err := l.Reopen(1); err == nil {
+		t.Errorf("expected an error reopening an active item")
+	}
+
+	l.Complete(1)
+	completedAt := l[0].CompletedAt
+
+	if err := l.ReopenID(l[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Done || !l[0].CompletedAt.IsZero() {
+		t.Errorf("expected the item to be active again: %+v", l[0])
+	}
+	if len(l[0].Reopened) != 1 || !l[0].Reopened[0].CompletedAt.Equal(completedAt) {
+		t.Errorf("expected the previous completion to be kept: %+v", l[0].Reopened)
+	}
+	if err := l.Reopen(2); err == nil {
+		t.Errorf("expected an error reopening a missing item")
+	}
+}
+
+// TestBulk will select items by ranges and lists and change them all at once
+func TestBulk(t *testing.T) {
+	var l todo.List
+	for i := 1; i <= 6; i++ {
+		l.Add(fmt.Sprintf("Task %d", i))
+	}
+
+	ids, err := l.Select("1-3, 5," + l[5].ID + ",2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{l[0].ID, l[1].ID, l[2].ID, l[4].ID, l[5].ID}
+	if fmt.Sprint(ids) != fmt.Sprint(exp) {
+		t.Errorf("expected %v; got %v instead", exp, ids)
+	}
+
+	for _, sel := range []string{"3-1", "0-2", "5-7", "x", ""} {
+		if _, err := l.Select(sel); err == nil {
+			t.Errorf("Select(%q): expected an error", sel)
+		}
+	}
+
+	if err := l.CompleteAll(ids[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.TagAll(ids[1:3], "+bulk"); err != nil {
+		t.Fatal(err)
+	}
+
+	// deleting removes every item at once, so the positions in the selection don't shift in between
+	del, _ := l.Select("2,4")
+	if err := l.DeleteAll(del); err != nil {
+		t.Fatal(err)
+	}
+	expString := "[x] 1: Task 1\n[ ] 2: Task 3 +bulk\n[ ] 3: Task 5\n[ ] 4: Task 6\n"
+	if l.String() != expString {
+		t.Errorf("expected %q; got %q instead", expString, l.String())
+	}
+
+	// an unknown ID fails without changing anything
+	if err := l.DeleteAll([]string{l[0].ID, "nope"}); err == nil {
+		t.Errorf("expected an error deleting an unknown ID")
+	}
+	if len(l) != 4 {
+		t.Errorf("expected %d items; got %d instead", 4, len(l))
+	}
+}
+
+// TestArchive will move completed items to an archive and restore one of them
+func TestArchive(t *testing.T) {
+	var l, archive todo.List
+	for _, task := range []string{"Task 1", "Task 2", "Task 3"} {
+		l.Add(task)
+	}
+	l.Complete(1)
+	l.Complete(3)
+
+	done := l.Filter(todo.Filter{Status: todo.StatusDone}).IDs()
+	if err := l.Archive(&archive, done); err != nil {
+		t.Fatal(err)
+	}
+	if l.String() != "[ ] 1: Task 2\n" {
+		t.Errorf("unexpected list %q", l.String())
+	}
+	if archive.String() != "[x] 1: Task 1\n[x] 2: Task 3\n" {
+		t.Errorf("unexpected archive %q", archive.String())
+	}
+
+	// restore keeps the ID and completion of the item
+	id := archive[1].ID
+	if err := l.Restore(&archive, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	if l[1].ID != id || !l[1].Done || len(archive) != 1 {
+		t.Errorf("unexpected restore: list %q, archive %q", l.String(), archive.String())
+	}
+
+	if err := l.Restore(&archive, []string{"nope"}); err == nil {
+		t.Errorf("expected an error restoring an unknown ID")
+	}
+
+	// archiving is undone in the List and the archive together
+	h := todo.NewHistory()
+	before, archiveBefore := l.Clone(), archive.Clone()
+	if err := l.Archive(&archive, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	h.RecordArchive("archive", before, l, archiveBefore, archive)
+	step, err := h.Undo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step.RevertArchive(&archive)
+	if fmt.Sprint(l) != fmt.Sprint(before) || fmt.Sprint(archive) != fmt.Sprint(archiveBefore) {
+		t.Errorf("expected the archive to be undone; got list %q, archive %q", l.String(), archive.String())
+	}
+
+	// subtasks are archived and restored along with their parent
+	l, archive = todo.List{}, todo.List{}
+	l.Add("Parent")
+	parent := l[0].ID
+	for _, task := range []string{"Child 1", "Child 2"} {
+		if err := l.AddSubtask(1, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.AddSubtask(2, "Grandchild")
+	l.Add("Other")
+	for _, ref := range []string{"1.1.1", "1.1", "1.2"} {
+		pos, err := l.Resolve(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Complete(pos); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Archive(&archive, []string{parent}); err != nil {
+		t.Fatal(err)
+	}
+	if l.String() != "[ ] 1: Other\n" || len(archive) != 4 {
+		t.Fatalf("unexpected archive: list %q, archive %q", l.String(), archive.String())
+	}
+	exp := "[x] 1: Parent\n  [x] 1.1: Child 1\n    [x] 1.1.1: Grandchild\n  [x] 1.2: Child 2\n"
+	if archive.String() != exp {
+		t.Errorf("expected archive %q; got %q instead", exp, archive.String())
+	}
+
+	if err := l.Restore(&archive, []string{parent}); err != nil {
+		t.Fatal(err)
+	}
+	if len(archive) != 0 || len(l) != 5 {
+		t.Errorf("expected the parent and it's subtasks to be restored; got list %q, archive %q", l.String(), archive.String())
+	}
+}
+
+// TestRecurrence will work out the next dates of the recurrence rules and complete a recurring item
+func TestRecurrence(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		rule string
+		from time.Time
+		exp  time.Time
+	}{
+		{"daily", friday, time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)},
+		{"weekdays", friday, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"weekly", friday, time.Date(2026, 10, 23, 9, 0, 0, 0, time.UTC)},
+		{"weekly:mon,thu", friday, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"every:3d", friday, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"every:2w", friday, time.Date(2026, 10, 30, 9, 0, 0, 0, time.UTC)},
+		{"monthly", friday, time.Date(2026, 11, 16, 9, 0, 0, 0, time.UTC)},
+		{"monthly:15", friday, time.Date(2026, 11, 15, 9, 0, 0, 0, time.UTC)},
+		{"monthly:31", friday, time.Date(2026, 10, 31, 9, 0, 0, 0, time.UTC)},
+		{"monthly:31", time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		got, err := todo.NextDate(c.rule, c.from)
+		if err != nil {
+			t.Errorf("NextDate(%q): %v", c.rule, err)
+			continue
+		}
+		if !got.Equal(c.exp) {
+			t.Errorf("NextDate(%q, %v): expected %v; got %v instead", c.rule, c.from, c.exp, got)
+		}
+	}
+
+	// rules are normalized
+	for in, exp := range map[string]string{"Weekly:Thursday,mon": "weekly:mon,thu", "every:2w": "every:14d", "none": ""} {
+		if got, err := todo.ParseRecurrence(in); err != nil || got != exp {
+			t.Errorf("ParseRecurrence(%q): expected %q; got %q (%v) instead", in, exp, got, err)
+		}
+	}
+	for _, in := range []string{"hourly", "daily:2", "weekly:t", "weekly:xyz", "every:0d", "monthly:32"} {
+		if _, err := todo.ParseRecurrence(in); err == nil {
+			t.Errorf("ParseRecurrence(%q): expected an error", in)
+		}
+	}
+
+	// completing a recurring item adds it's next occurrence
+	var l todo.List
+	l.Add("Water plants")
+	l.AddTags(1, "@home")
+	l.SetRecurrence(1, "weekly")
+	due := time.Date(2100, 1, 4, 0, 0, 0, 0, time.UTC)
+	l.SetDue(1, due)
+
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 {
+		t.Fatalf("expected %d items; got %d instead", 2, len(l))
+	}
+	next := l[1]
+	if next.Done || next.ID == l[0].ID || next.Recur != "weekly" || !next.Due.Equal(due.AddDate(0, 0, 7)) {
+		t.Errorf("unexpected next occurrence %+v", next)
+	}
+	if l.String() != "[x] 1: Water plants @home\n[ ] 2: Water plants @home\n" {
+		t.Errorf("unexpected list %q", l.String())
+	}
+
+	// completing it again doesn't add another one
+	l.Complete(1)
+	if len(l) != 2 {
+		t.Errorf("expected %d items; got %d instead", 2, len(l))
+	}
+
+	// nor does reopening it and completing it once more, while the next occurrence is still on the list
+	if err := l.Reopen(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[0].Next != next.ID {
+		t.Errorf("expected the occurrence %q to be kept; got %v", next.ID, l)
+	}
+
+	// once that occurrence is deleted, completing the item again creates a new one
+	l.Reopen(1)
+	l.Delete(2)
+	l.Complete(1)
+	if len(l) != 2 || l[1].ID == next.ID || l[0].Next != l[1].ID {
+		t.Errorf("expected a new next occurrence; got %v", l)
+	}
+
+	// a monthly item due on the 31st is anchored to that day, so it doesn't drift after a short month
+	l = todo.List{}
+	l.Add("Pay rent")
+	l.SetDue(1, time.Date(2100, 1, 31, 0, 0, 0, 0, time.UTC))
+	l.SetRecurrence(1, "monthly")
+	if l[0].Recur != "monthly:31" {
+		t.Errorf("expected the rule to be anchored as %q; got %q instead", "monthly:31", l[0].Recur)
+	}
+	for idx, exp := range []time.Time{
+		time.Date(2100, 2, 28, 0, 0, 0, 0, time.UTC),
+		time.Date(2100, 3, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2100, 4, 30, 0, 0, 0, 0, time.UTC),
+	} {
+		if err := l.Complete(idx + 1); err != nil {
+			t.Fatal(err)
+		}
+		if got := l[idx+1].Due; !got.Equal(exp) {
+			t.Errorf("expected occurrence %d to be due on %v; got %v instead", idx+2, exp, got)
+		}
+	}
+}
+
+// TestSubtasks will build a tree of subtasks, check how it's displayed and the completion and delete rules
+func TestSubtasks(t *testing.T) {
+	var l todo.List
+	l.Add("Plan trip")
+	l.Add("Buy milk")
+	l.AddSubtask(1, "Book flights")
+	l.AddSubtask(1, "Book hotel")
+	l.AddSubtask(3, "Compare prices")
+
+	exp := "[ ] 1: Plan trip\n  [ ] 1.1: Book flights\n    [ ] 1.1.1: Compare prices\n  [ ] 1.2: Book hotel\n[ ] 2: Buy milk\n"
+	if l.String() != exp {
+		t.Errorf("expected %q; got %q instead", exp, l.String())
+	}
+
+	// the numbers on the tree resolve to the positions
+	for ref, pos := range map[string]int{"1": 1, "2": 2, "1.1": 3, "1.2": 4, "1.1.1": 5} {
+		if got, err := l.Resolve(ref); err != nil || got != pos {
+			t.Errorf("Resolve(%q): expected %d; got %d (%v) instead", ref, pos, got, err)
+		}
+	}
+	if _, err := l.Resolve("1.3"); err == nil {
+		t.Errorf("expected an error resolving a missing subtask")
+	}
+
+	// a task can't be moved under it's own subtask
+	if err := l.SetParent(1, 5); err == nil {
+		t.Errorf("expected an error moving a task under it's subtask")
+	}
+
+	// parents can't be completed with open subtasks, and complete with their last subtask
+	if err := l.Complete(1); err == nil {
+		t.Errorf("expected an error completing a task with open subtasks")
+	}
+	l.Complete(5)
+	if !l[2].Done || l[0].Done {
+		t.Errorf("expected only %q to be completed along with it's subtask", l[2].Task)
+	}
+	l.Complete(4)
+	if !l[0].Done {
+		t.Errorf("expected %q to be completed with it's last subtask", l[0].Task)
+	}
+
+	// reopening a subtask reopens it's parents
+	l.Reopen(5)
+	if l[0].Done || l[2].Done || !l[3].Done {
+		t.Errorf("unexpected list after reopen %q", l.String())
+	}
+
+	// the whole tree can be completed at once
+	ids, _ := l.Select("1,1.1,1.1.1")
+	if err := l.CompleteAll(ids); err != nil {
+		t.Fatal(err)
+	}
+	if !l[0].Done || !l[4].Done {
+		t.Errorf("unexpected list after CompleteAll %q", l.String())
+	}
+
+	// deleting a task deletes it's subtasks
+	l.Delete(1)
+	if l.String() != "[ ] 1: Buy milk\n" {
+		t.Errorf("unexpected list after delete %q", l.String())
+	}
+}
+
+// TestDependencies will link items, refuse loops and check which items are blocked or ready
+func TestDependencies(t *testing.T) {
+	var l todo.List
+	for _, task := range []string{"Run migration", "Deploy", "Announce", "Write docs"} {
+		l.Add(task)
+	}
+	if err := l.Block(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Block(3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// loops are refused, directly or through other items
+	for _, c := range [][2]int{{1, 1}, {1, 2}, {1, 3}} {
+		if err := l.Block(c[0], c[1]); err == nil {
+			t.Errorf("Block(%d, %d): expected an error", c[0], c[1])
+		}
+	}
+
+	exp := "[ ] 2: Deploy\n[ ] 3: Announce\n"
+	if got := l.View().Blocked(&l).String(); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+	exp = "[ ] 1: Run migration\n[ ] 4: Write docs\n"
+	if got := l.View().Ready(&l).String(); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+
+	// completing a blocker unblocks the items depending on it
+	l.Complete(1)
+	exp = "[ ] 2: Deploy\n[ ] 4: Write docs\n"
+	if got := l.View().Ready(&l).String(); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+
+	if err := l.Unblock(3, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Blockers(3)) != 0 || l.Unblock(3, 2) == nil {
+		t.Errorf("expected %q to no longer depend on %q", l[2].Task, l[1].Task)
+	}
+}
+
+// TestNotes will clean up the notes of an item and check they're displayed under it
+func TestNotes(t *testing.T) {
+	exp := "first line\n\n  indented line"
+	if got := todo.CleanNotes("\r\n\nfirst line  \r\n\n  indented line\t\n\n"); got != exp {
+		t.Errorf("expected %q; got %q instead", exp, got)
+	}
+
+	var l todo.List
+	l.Add("Buy milk")
+	l.SetNotes(1, "semi-skimmed\nfrom the farm shop")
+
+	now := time.Now()
+	v := l.View()
+	if got := v.Verbose(now); !strings.HasSuffix(got, "Status: Active\n    semi-skimmed\n    from the farm shop\n") {
+		t.Errorf("expected the notes in %q", got)
+	}
+	if got := v[0].Details(now); !strings.HasPrefix(got, "Task:       Buy milk\n") ||
+		!strings.HasSuffix(got, "\n\n  semi-skimmed\n  from the farm shop\n") {
+		t.Errorf("unexpected details %q", got)
+	}
+
+	// notes are left out of the plain listing
+	if l.String() != "[ ] 1: Buy milk\n" {
+		t.Errorf("unexpected list %q", l.String())
+	}
+}
+
+// TestTodoTxt will read todo.txt lines, write them back and import an exported List into itself without changes
+func TestTodoTxt(t *testing.T) {
+	lines := "x 2026-10-17 2026-10-01 Pay rent +home @online due:2026-10-20 pri:A id:a1b2c3d4\n" +
+		"(B) 2026-10-02 Call the bank at 10:30 https://bank.example tag:urgent rec:weekly:mon t:2026-10-05 id:e5f6a7b8\n" +
+		"Write docs parent:a1b2c3d4 dep:e5f6a7b8 id:c0ffee00\n"
+
+	var l todo.List
+	added, updated, err := l.ImportTodoTxt(strings.NewReader(lines + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 3 || updated != 0 {
+		t.Errorf("expected 3 added and 0 updated; got %d and %d instead", added, updated)
+	}
+	if !l[0].Done || l[0].Priority != "A" || l[0].Task != "Pay rent" || l[1].Recur != "weekly:mon" || l[2].Parent != "a1b2c3d4" {
+		t.Errorf("unexpected items %+v", l)
+	}
+
+	out := strings.Builder{}
+	if err := l.View().WriteTodoTxt(&out); err != nil {
+		t.Fatal(err)
+	}
+	// the tree order puts the subtask after it's parent. Lines without a creation date were created when imported
+	created := l[2].CreatedAt.Local()
+	exp := "x 2026-10-17 2026-10-01 Pay rent +home @online due:2026-10-20 pri:A id:a1b2c3d4\n" +
+		created.Format("2006-01-02") + " Write docs parent:a1b2c3d4 dep:e5f6a7b8 created:" +
+		created.Format("2006-01-02T15:04:05") + " id:c0ffee00\n" +
+		"(B) 2026-10-02 Call the bank at 10:30 https://bank.example tag:urgent rec:weekly:mon t:2026-10-05 id:e5f6a7b8\n"
+	if out.String() != exp {
+		t.Errorf("expected %q; got %q instead", exp, out.String())
+	}
+
+	// a List read back from it's own export doesn't change, keeping the times and notes
+	var m todo.List
+	m.Add("Buy milk")
+	m.AddTags(1, "+shop", "urgent")
+	m.SetNotes(1, "semi-skimmed")
+	m.SetDue(1, time.Now().Add(time.Hour).Truncate(time.Minute))
+	m.Add("Call mom")
+	m.Complete(2)
+	before := m.Clone()
+
+	out.Reset()
+	m.View().WriteTodoTxt(&out)
+	if added, updated, err := m.ImportTodoTxt(strings.NewReader(out.String())); err != nil || added != 0 || updated != 0 {
+		t.Errorf("expected no changes; got %d added and %d updated (%v)", added, updated, err)
+	}
+	if fmt.Sprint(m) != fmt.Sprint(before) || m[0].Notes != "semi-skimmed" {
+		t.Errorf("expected %v; got %v instead", before, m)
+	}
+
+	// an export imported into an empty List keeps the text, notes and times. Words that look like something else
+	// are escaped
+	var e todo.List
+	e.Add("Reply re:budget to Ann +maybe")
+	e.SetNotes(1, "see the thread\nand 50% of Q3 / Q4")
+	e.Add("x 2026-10-01 \\ (A)")
+	e.Complete(2)
+	txt := strings.Builder{}
+	e.View().WriteTodoTxt(&txt)
+	if !strings.Contains(txt.String(), `Reply \re:budget to Ann \+maybe`) {
+		t.Errorf("expected the words to be escaped; got %q", txt.String())
+	}
+	var f todo.List
+	if _, _, err := f.ImportTodoTxt(strings.NewReader(txt.String())); err != nil {
+		t.Fatal(err)
+	}
+	for idx := range e {
+		if f[idx].Task != e[idx].Task || f[idx].Notes != e[idx].Notes || f[idx].Done != e[idx].Done ||
+			!f[idx].CreatedAt.Equal(e[idx].CreatedAt.Truncate(time.Second)) ||
+			!f[idx].CompletedAt.Equal(e[idx].CompletedAt.Truncate(time.Second)) {
+			t.Errorf("expected %+v; got %+v instead", e[idx], f[idx])
+		}
+	}
+
+	// changes made in another tool update the items
+	changed := strings.Replace(out.String(), "Call mom", "Call dad", 1)
+	if _, updated, _ := m.ImportTodoTxt(strings.NewReader(changed)); updated != 1 || m[1].Task != "Call dad" {
+		t.Errorf("expected the task to be updated; got %q", m[1].Task)
+	}
+
+	if _, _, err := m.ImportTodoTxt(strings.NewReader("New task\nBad due:tomorrow\n")); err == nil || len(m) != 2 {
+		t.Errorf("expected an error and no changes; got %v", err)
+	}
+
+	// dependencies that would make items block each other are rejected, like Block does
+	cycle := "First dep:bbbb2222 id:aaaa1111\nSecond dep:aaaa1111 id:bbbb2222\n"
+	if _, _, err := m.ImportTodoTxt(strings.NewReader(cycle)); err == nil || len(m) != 2 {
+		t.Errorf("expected an error and no changes; got %v", err)
+	}
+	if _, _, err := m.ImportTodoTxt(strings.NewReader("Itself dep:cccc3333 id:cccc3333\n")); err == nil || len(m) != 2 {
+		t.Errorf("expected an error and no changes; got %v", err)
+	}
+
+	// undated lines get the time they're imported, so they pass validation
+	var u todo.List
+	if _, _, err := u.ImportTodoTxt(strings.NewReader("Call mom\nx Done thing\n")); err != nil {
+		t.Fatal(err)
+	}
+	if u[0].CreatedAt.IsZero() || u[1].CreatedAt.IsZero() || u[1].CompletedAt.IsZero() || !u[0].CompletedAt.IsZero() {
+		t.Errorf("unexpected dates %+v", u)
+	}
+	if problems := u.Validate(); len(problems) != 0 {
+		t.Errorf("expected no problems; got %v", problems)
+	}
+}
+
+// TestExport will write a List as CSV and as a Markdown checklist
+func TestExport(t *testing.T) {
+	var l todo.List
+	l.Add("Plan trip")
+	l.AddSubtask(1, "Book flights, hotel")
+	l.SetNotes(2, "window seat\naisle is fine too")
+	l.Add("Pay rent")
+	l.AddTags(3, "+home")
+	l.SetPriority(3, "A")
+	l.Complete(3)
+
+	out := strings.Builder{}
+	if err := l.View().WriteCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if lines[0] != "position,id,task,done,priority,tags,due,created,completed,notes" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	// the task with a comma and the multi-line notes are quoted
+	if !strings.HasPrefix(lines[2], "1.1,"+l[1].ID+",\"Book flights, hotel\",false,,,,") || lines[3] != "aisle is fine too\"" {
+		t.Errorf("unexpected CSV %q", out.String())
+	}
+	if !strings.HasPrefix(lines[4], "2,"+l[2].ID+",Pay rent,true,A,+home,,") {
+		t.Errorf("unexpected CSV %q", out.String())
+	}
+
+	out.Reset()
+	if err := l.View().WriteMarkdown(&out); err != nil {
+		t.Fatal(err)
+	}
+	exp := "- [ ] Plan trip\n  - [ ] Book flights, hotel\n    window seat\n    aisle is fine too\n- [x] (A) Pay rent +home\n"
+	if out.String() != exp {
+		t.Errorf("expected %q; got %q instead", exp, out.String())
+	}
+}
+
+// TestAddBatch will add a task for every line, with it's inline metadata
+func TestAddBatch(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	input := "(A) Pay rent +home due:tomorrow\n\n   \nBuy milk @shop\nCall the bank due:2026-11-01\n"
+
+	var l todo.List
+	n, err := l.AddBatch(strings.NewReader(input), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected %d tasks added; got %d instead", 3, n)
+	}
+	exp := "[ ] 1: (A) Pay rent +home\n[ ] 2: Buy milk @shop\n[ ] 3: Call the bank\n"
+	if l.String() != exp {
+		t.Errorf("expected %q; got %q instead", exp, l.String())
+	}
+	if !l[0].Due.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) || !l[1].Due.IsZero() {
+		t.Errorf("unexpected due dates %v and %v", l[0].Due, l[1].Due)
+	}
+
+	// nothing is added when a line is invalid
+	if _, err := l.AddBatch(strings.NewReader("Task 4\nTask 5 due:someday\n"), now); err == nil || len(l) != 3 {
+		t.Errorf("expected an error and no tasks added; got %v", err)
+	}
+}
+
+// TestDiff will diff two Lists and check the kind and position of every change
+func TestDiff(t *testing.T) {
+	var before todo.List
+	before.Add("Task 1")
+	before.Add("Task 2")
+	before.Add("Task 3")
+
+	after := before.Clone()
+	if err := after.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := after.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	after.Add("Task 4")
+
+	changes := todo.Diff(before, after)
+	kinds := []string{}
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind())
+	}
+	if strings.Join(kinds, " ") != "completed deleted added" {
+		t.Fatalf("unexpected changes %v", kinds)
+	}
+
+	// deleted items keep the position they had, the others are on their new position
+	for idx, pos := range []int{1, 2, 3} {
+		if e := changes[idx].Entry(); e.Pos != pos {
+			t.Errorf("expected %q on position %d; got %d instead", e.Task, pos, e.Pos)
+		}
+	}
+	if e := changes[1].Entry(); e.Task != "Task 2" {
+		t.Errorf("expected the deleted task to be %q; got %q instead", "Task 2", e.Task)
+	}
+}
+
+// TestMigrate will upgrade a legacy bare array file and check files of other versions are handled
+func TestMigrate(t *testing.T) {
+	// version 0: a bare array. The first item was saved before IDs were added
+	legacy := `[{"Task":"Task 1","Done":false,"CreatedAt":"2024-01-02T10:00:00Z"},{"ID":"a1b2c3d4","Task":"Task 2","Done":true}]`
+
+	data, version, err := todo.Migrate([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Errorf("expected the legacy file to be version %d; got %d instead", 0, version)
+	}
+	if !strings.HasPrefix(string(data), fmt.Sprintf(`{"version":%d,"items":[`, todo.SchemaVersion)) {
+		t.Errorf("expected the items inside the envelope; got %s", data)
+	}
+
+	// upgraded files are left alone
+	again, version, err := todo.Migrate(data)
+	if err != nil || version != todo.SchemaVersion || string(again) != string(data) {
+		t.Errorf("expected the upgraded file to be unchanged; got version %d, %v: %s", version, err, again)
+	}
+
+	// Get upgrades legacy files, and Save writes them in the current version
+	tf := filepath.Join(t.TempDir(), "todo.json")
+	if err := os.WriteFile(tf, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var l todo.List
+	if err := l.Get(tf); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[0].ID == "" || l[0].ID == "a1b2c3d4" || l[1].ID != "a1b2c3d4" || !l[1].Done {
+		t.Fatalf("unexpected items %+v", l)
+	}
+	if !l[0].CreatedAt.Equal(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the creation date to be kept; got %v", l[0].CreatedAt)
+	}
+	if err := l.Save(tf); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, version, _ := todo.Migrate(saved); version != todo.SchemaVersion {
+		t.Errorf("expected the file to be saved in version %d; got %d instead", todo.SchemaVersion, version)
+	}
+
+	// items added to a current file by hand without an ID get one as well
+	if err := os.WriteFile(tf, []byte(`{"version":1,"items":[{"Task":"Task 1"},{"Task":"Task 2"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Get(tf); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].ID == "" || l[1].ID == "" || l[0].ID == l[1].ID {
+		t.Errorf("expected every item to get it's own ID; got %q and %q", l[0].ID, l[1].ID)
+	}
+
+	// files of a newer version, or without one, are refused
+	for _, data := range []string{`{"version":99,"items":[]}`, `{"items":[]}`} {
+		if _, _, err := todo.Migrate([]byte(data)); err == nil {
+			t.Errorf("expected an error migrating %s", data)
+		}
+	}
+}
+
+// TestValidate will salvage a damaged file, report the problems of it's items and repair them
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	created := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	// a file edited by hand: the first item is fine, the second has a completion date without being done and no
+	// creation date, and the third has the ID of the first. The fourth isn't a valid item.
+	data := `{"version":1,"items":[
+		{"ID":"a1b2c3d4","Task":"Task 1","CreatedAt":"2026-10-01T09:00:00Z"},
+		{"ID":"e5f6a7b8","Task":"Task 2","CompletedAt":"2026-10-02T09:00:00Z","ModifiedAt":"2026-10-03T09:00:00Z"},
+		{"ID":"a1b2c3d4","Task":"Task 3","CreatedAt":"2026-10-01T09:00:00Z"},
+		{"ID":"c9d0e1f2","Task":"Task 4","Done":"yes"},
+		{"ID":"a3b4c5d6","Task":"Task 5","CreatedAt":"2026-10-01T09:00:00Z"}
+	]}`
+
+	l, problems := todo.Salvage([]byte(data))
+	if len(l) != 4 || len(problems) != 1 || !strings.HasPrefix(problems[0].String(), "item 4 was dropped") {
+		t.Fatalf("expected 4 items and 1 problem; got %d and %v", len(l), problems)
+	}
+
+	exp := []string{
+		"item 2 (e5f6a7b8): is not done but has a completion date",
+		"item 2 (e5f6a7b8): has no creation date",
+		"item 3 (a1b2c3d4): has the same ID as item 1",
+	}
+	for _, problems := range [][]todo.Problem{l.Validate(), l.Repair(now)} {
+		got := []string{}
+		for _, p := range problems {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(exp, "\n") {
+			t.Errorf("expected problems %q; got %q instead", exp, got)
+		}
+	}
+
+	// the repaired List has no problems left
+	if problems := l.Validate(); len(problems) != 0 {
+		t.Errorf("expected no problems after the repair; got %v", problems)
+	}
+	if !l[1].CompletedAt.IsZero() || !l[1].CreatedAt.Equal(created.AddDate(0, 0, 2)) {
+		t.Errorf("unexpected dates %v and %v", l[1].CompletedAt, l[1].CreatedAt)
+	}
+	if l[0].ID != "a1b2c3d4" || l[2].ID == "a1b2c3d4" {
+		t.Errorf("expected the second item with the ID to get a new one; got %q and %q", l[0].ID, l[2].ID)
+	}
+
+	// truncated files keep the items before the cut
+	l, problems = todo.Salvage([]byte(data[:strings.Index(data, "Task 3")]))
+	if len(l) != 2 || len(problems) != 1 {
+		t.Errorf("expected 2 items and 1 problem; got %d and %v", len(l), problems)
+	}
+	l, problems = todo.Salvage([]byte(`{"version":1,"items":[{"ID":"a1b2c3d4","Task":"Task 1"}]}}}garbage`))
+	if len(l) != 1 || len(problems) != 1 {
+		t.Errorf("expected 1 item and a problem with the end of the file; got %d and %v", len(l), problems)
+	}
+	l, problems = todo.Salvage([]byte(`not json`))
+	if len(l) != 0 || len(problems) != 1 {
+		t.Errorf("expected no items and 1 problem; got %d and %v", len(l), problems)
+	}
+}
+
+// TestBackups will parse backup policies, save a List a few times and rotate and load it's backups
+func TestBackups(t *testing.T) {
+	for in, exp := range map[string]string{"5": "5", "off": "off", "0": "off", "daily": "daily:7", "Daily:3": "daily:3"} {
+		p, err := todo.ParseBackupPolicy(in)
+		if err != nil || p.String() != exp {
+			t.Errorf("expected %q to parse as %q; got %q, %v", in, exp, p, err)
+		}
+	}
+	for _, in := range []string{"-1", "weekly", "daily3", "daily:x"} {
+		if _, err := todo.ParseBackupPolicy(in); err == nil {
+			t.Errorf("expected an error parsing %q", in)
+		}
+	}
+
+	// keep the last 2 backups, one per save
+	tf := filepath.Join(t.TempDir(), "todo.json")
+	store := todo.NewJSONStore(tf)
+	store.BackupPolicy = todo.BackupPolicy{Keep: 2}
+
+	var l todo.List
+	for i := 1; i <= 4; i++ {
+		l.Add(fmt.Sprintf("Task %d", i))
+		if err := store.Save(&l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first save had no file to back up, and the oldest backup was removed
+	backups, err := store.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected %d backups; got %d instead", 2, len(backups))
+	}
+	var restored todo.List
+	if err := store.LoadBackup(backups[0].Name, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 2 || restored[1].Task != "Task 2" {
+		t.Errorf("expected the oldest backup to hold 2 tasks; got %v", restored)
+	}
+	if err := store.LoadBackup("20000101", &restored); err == nil {
+		t.Error("expected an error loading a backup that doesn't exist")
+	}
+
+	// saving a List that hasn't changed leaves the backups alone
+	for i := 0; i < 3; i++ {
+		if err := store.Save(&l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if again, err := store.Backups(); err != nil || len(again) != 2 || again[0].Name != backups[0].Name {
+		t.Errorf("expected the backups to stay %v; got %v, %v", backups, again, err)
+	}
+
+	// daily backups hold the list as it was before the first save of the day, and leave the others alone
+	store.BackupPolicy = todo.BackupPolicy{Keep: 1, Daily: true}
+	for i := 5; i <= 6; i++ {
+		l.Add(fmt.Sprintf("Task %d", i))
+		if err := store.Save(&l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err = store.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 || !backups[0].Daily {
+		t.Fatalf("expected 2 backups and a daily one; got %v", backups)
+	}
+	if err := store.LoadBackup(backups[0].Name, &restored); err != nil || len(restored) != 4 {
+		t.Errorf("expected the daily backup to hold 4 tasks; got %d, %v", len(restored), err)
+	}
+}
@@ -0,0 +1,421 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+//=====================
+// TODO.TXT FORMAT
+//=====================
+// Items can be written to and read from the todo.txt format (https://github.com/todotxt/todo.txt), one item per line:
+//   x 2026-10-17 2026-10-01 Pay rent +home @online due:2026-10-20 pri:A id:a1b2c3d4
+//   (A) 2026-10-01 Call the bank +money id:e5f6a7b8
+// Completed items start with an x and their completion date. Active items start with their priority. Both are
+// followed by the creation date. The fields todo.txt has no place for are kept as key:value pairs:
+//   due:     the due date. eg.: due:2026-10-20 or due:2026-10-20T09:15
+//   rec:     the recurrence rule. eg.: rec:weekly:mon
+//   pri:     the priority of a completed item, as todo.txt drops the (A) once an item is done
+//   tag:     a plain tag, one that isn't a +project or @context
+//   parent:  the ID of the parent of a subtask
+//   dep:       the IDs of the items blocking the item, separated by commas
+//   note:      the notes of the item, escaped so they fit in a single word. eg.: note:semi-skimmed%0Aor+whole
+//   created:   the creation time, when it's not midnight. eg.: created:2026-10-01T09:15:04
+//   completed: the completion time, when it's not midnight
+//   id:        the ID of the item, so reading the file back updates the items instead of adding copies
+// Any other key:value pair is kept as it is on the item's Extra field and written back.
+// Words of the task that would be read as something else (a key:value pair, a +project or @context token, or a
+// leading x, priority or date) are escaped with a backslash, as are words starting with one. eg.: Reply \re:budget
+// Dates are written in local time. The created: and completed: times are only used when they fall on the day of the
+// line's dates, so changing a date in another tool still works. Reading a line back keeps the time of an item's dates
+// when the day didn't change, along with what todo.txt can't hold at all (edit and reopen history).
+// Lines without a creation date, or completed lines without a completion date, get the time they're imported.
+// The +project and @context tokens are always written at the end of the task.
+
+// todoTxtDate is the layout of the dates in the todo.txt format
+const todoTxtDate = "2006-01-02"
+
+// todoTxtDueTime is the layout of due dates that have a time. Spaces would split the key:value pair.
+const todoTxtDueTime = "2006-01-02T15:04"
+
+// todoTxtTime is the layout of the created: and completed: times
+const todoTxtTime = "2006-01-02T15:04:05"
+
+// WriteTodoTxt will write the entries of the View to w in the todo.txt format, one per line
+func (v View) WriteTodoTxt(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range v {
+		if _, err := fmt.Fprintln(bw, e.todoTxt()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// todoTxt formats the item as a todo.txt line
+func (i item) todoTxt() string {
+	fields := []string{}
+
+	// the completion mark and date, or the priority, followed by the creation date
+	if i.Done {
+		fields = append(fields, "x")
+		if !i.CompletedAt.IsZero() {
+			fields = append(fields, i.CompletedAt.Local().Format(todoTxtDate))
+		}
+	} else if i.Priority != "" {
+		fields = append(fields, "("+i.Priority+")")
+	}
+	// a single date after the x is the completion date, so the creation date needs the completion date before it
+	if !i.CreatedAt.IsZero() && (!i.Done || !i.CompletedAt.IsZero()) {
+		fields = append(fields, i.CreatedAt.Local().Format(todoTxtDate))
+	}
+
+	for idx, word := range strings.Fields(i.Task) {
+		fields = append(fields, escapeTodoTxtWord(word, idx == 0))
+	}
+
+	// the tags. Plain tags are written as key:value pairs, as todo.txt wouldn't tell them apart from the text
+	for _, tag := range i.Tags {
+		if isTagToken(tag) {
+			fields = append(fields, tag)
+			continue
+		}
+		fields = append(fields, "tag:"+tag)
+	}
+
+	// then the fields todo.txt has no place for
+	if !i.Due.IsZero() {
+		d := i.Due.Local()
+		due := d.Format(todoTxtDate)
+		if !d.Equal(startOfDay(d)) {
+			due = d.Format(todoTxtDueTime)
+		}
+		fields = append(fields, "due:"+due)
+	}
+	if i.Recur != "" {
+		fields = append(fields, "rec:"+i.Recur)
+	}
+	if i.Done && i.Priority != "" {
+		fields = append(fields, "pri:"+i.Priority)
+	}
+	if i.Parent != "" {
+		fields = append(fields, "parent:"+i.Parent)
+	}
+	if len(i.BlockedBy) > 0 {
+		fields = append(fields, "dep:"+strings.Join(i.BlockedBy, ","))
+	}
+	if i.Notes != "" {
+		fields = append(fields, "note:"+url.QueryEscape(i.Notes))
+	}
+	if c := i.CreatedAt.Local(); !c.IsZero() && !c.Equal(startOfDay(c)) {
+		fields = append(fields, "created:"+c.Format(todoTxtTime))
+	}
+	if c := i.CompletedAt.Local(); i.Done && !c.IsZero() && !c.Equal(startOfDay(c)) {
+		fields = append(fields, "completed:"+c.Format(todoTxtTime))
+	}
+	fields = append(fields, i.Extra...)
+	if i.ID != "" {
+		fields = append(fields, "id:"+i.ID)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// escapeTodoTxtWord escapes a word of the task with a backslash when reading it back would take it for something
+// other than text. The first word is also escaped when it would be read as the completion mark, priority or a date.
+func escapeTodoTxtWord(word string, first bool) string {
+	_, _, pair := todoTxtPair(word)
+	_, priority := priorityToken(word)
+	_, date := parseTodoTxtDate([]string{word})
+	if pair || isTagToken(word) || strings.HasPrefix(word, `\`) || first && (word == "x" || priority || date) {
+		return `\` + word
+	}
+	return word
+}
+
+// ImportTodoTxt will read todo.txt lines from r into the List. Lines with the id: of an item on the List update it;
+// the others are added to the end of the List. Blank lines are skipped. Nothing is changed if any of the lines is
+// invalid, including lines whose dep: would make items block each other (see Block). It returns the number of items
+// added and updated.
+func (l *List) ImportTodoTxt(r io.Reader) (added, updated int, err error) {
+	// parse every line before changing anything
+	items := []item{}
+	lines := []int{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		it, err := parseTodoTxt(scanner.Text())
+		if err != nil {
+			return 0, 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		items = append(items, it)
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	// the lines are applied to a copy of the List, so the dependencies can be checked before changing anything
+	imported := l.Clone()
+	positions := make([]int, len(items))
+	now := time.Now()
+	for idx, it := range items {
+		pos, err := imported.Position(it.ID)
+		if it.ID == "" || err != nil {
+			if it.ID == "" {
+				it.ID = imported.newID()
+			}
+			// lines without dates were created, or completed, as far as the List knows, when they're imported
+			if it.CreatedAt.IsZero() {
+				it.CreatedAt = now
+			}
+			if it.Done && it.CompletedAt.IsZero() {
+				it.CompletedAt = now
+			}
+			imported = append(imported, it)
+			positions[idx] = len(imported)
+			added++
+			continue
+		}
+		positions[idx] = pos
+		if imported[pos-1].merge(it, now) {
+			updated++
+		}
+	}
+
+	// an item blocked by itself, directly or through other items, could never be started
+	for idx, pos := range positions {
+		if it := imported[pos-1]; len(it.BlockedBy) > 0 && imported.dependsOn(pos, it.ID) {
+			return 0, 0, fmt.Errorf("line %d: %q can't be blocked by it's own dependencies", lines[idx], it.Task)
+		}
+	}
+
+	*l = imported
+	return added, updated, nil
+}
+
+// merge will update the item with the fields read from a todo.txt line. The times of the dates are kept when the
+// day didn't change, along with the fields todo.txt doesn't hold. It reports whether anything changed.
+func (i *item) merge(from item, now time.Time) bool {
+	merged := *i
+	merged.Task = from.Task
+	merged.Done = from.Done
+	merged.Priority = from.Priority
+	merged.Due = from.Due
+	merged.Tags = from.Tags
+	merged.Recur = from.Recur
+	merged.Parent = from.Parent
+	merged.BlockedBy = from.BlockedBy
+	merged.Notes = from.Notes
+	merged.Extra = from.Extra
+
+	// a line without dates keeps the dates of the item. An item completed by the line is completed now
+	if !from.CreatedAt.IsZero() && !sameDay(i.CreatedAt, from.CreatedAt) {
+		merged.CreatedAt = from.CreatedAt
+	}
+	switch {
+	case !from.Done:
+		merged.CompletedAt = time.Time{}
+	case from.CompletedAt.IsZero():
+		if !i.Done || i.CompletedAt.IsZero() {
+			merged.CompletedAt = now
+		}
+	case !sameDay(i.CompletedAt, from.CompletedAt):
+		merged.CompletedAt = from.CompletedAt
+	}
+	if !i.Due.IsZero() && from.Due.Equal(i.Due) {
+		merged.Due = i.Due
+	}
+
+	// don't count nil and empty slices as a change
+	if len(merged.Tags) == 0 && len(i.Tags) == 0 {
+		merged.Tags = i.Tags
+	}
+	if len(merged.BlockedBy) == 0 && len(i.BlockedBy) == 0 {
+		merged.BlockedBy = i.BlockedBy
+	}
+	if len(merged.Extra) == 0 && len(i.Extra) == 0 {
+		merged.Extra = i.Extra
+	}
+	if reflect.DeepEqual(merged, *i) {
+		return false
+	}
+
+	merged.ModifiedAt = now
+	*i = merged
+	return true
+}
+
+// sameDay reports whether two dates fall on the same local day. Zero dates are only on the same day as each other.
+func sameDay(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return a.IsZero() && b.IsZero()
+	}
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// parseTodoTxt parses a todo.txt line into an item
+func parseTodoTxt(line string) (item, error) {
+	it := item{}
+	fields := strings.Fields(line)
+
+	// the completion mark and date, or the priority, followed by the creation date
+	if len(fields) > 0 && fields[0] == "x" {
+		it.Done = true
+		fields = fields[1:]
+		if d, ok := parseTodoTxtDate(fields); ok {
+			it.CompletedAt = d
+			fields = fields[1:]
+		}
+	} else if p, ok := priorityToken(firstField(fields)); ok {
+		it.Priority = p
+		fields = fields[1:]
+	}
+	if !it.Done || !it.CompletedAt.IsZero() {
+		if d, ok := parseTodoTxtDate(fields); ok {
+			it.CreatedAt = d
+			fields = fields[1:]
+		}
+	}
+	createdOn, completedOn := it.CreatedAt, it.CompletedAt
+
+	// the text, tags and key:value pairs. Escaped words are always text
+	words := []string{}
+	for _, field := range fields {
+		if word, ok := strings.CutPrefix(field, `\`); ok {
+			words = append(words, word)
+			continue
+		}
+		if isTagToken(field) {
+			if !it.hasTag(field) {
+				it.Tags = append(it.Tags, field)
+			}
+			continue
+		}
+
+		key, value, ok := todoTxtPair(field)
+		if !ok {
+			words = append(words, field)
+			continue
+		}
+		if err := it.setTodoTxtPair(key, value); err != nil {
+			return it, err
+		}
+	}
+
+	// the created: and completed: times only count on the day of the line's dates
+	if !createdOn.IsZero() && !sameDay(createdOn, it.CreatedAt) {
+		it.CreatedAt = createdOn
+	}
+	switch {
+	case !it.Done:
+		it.CompletedAt = time.Time{}
+	case !completedOn.IsZero() && !sameDay(completedOn, it.CompletedAt):
+		it.CompletedAt = completedOn
+	}
+
+	it.Task = strings.Join(words, " ")
+	if it.Task == "" {
+		return it, fmt.Errorf("task cannot be blank")
+	}
+	return it, nil
+}
+
+// firstField returns the first of the fields, or an empty string when there are none
+func firstField(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// parseTodoTxtDate parses the first field as a date
+func parseTodoTxtDate(fields []string) (time.Time, bool) {
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	d, err := time.ParseInLocation(todoTxtDate, fields[0], time.Local)
+	return d, err == nil
+}
+
+// todoTxtPair splits a key:value pair. The key has to start with a letter and the value can't start with a slash,
+// so times (10:30) and links (https://...) are kept as part of the text.
+func todoTxtPair(field string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(field, ":")
+	if !ok || key == "" || value == "" || strings.HasPrefix(value, "/") {
+		return "", "", false
+	}
+	if c := key[0]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// setTodoTxtPair sets the field of the item held by a key:value pair. Unknown keys are kept in Extra.
+func (i *item) setTodoTxtPair(key, value string) error {
+	switch key {
+	case "due":
+		d, err := time.ParseInLocation(todoTxtDueTime, value, time.Local)
+		if err != nil {
+			if d, err = time.ParseInLocation(todoTxtDate, value, time.Local); err != nil {
+				return fmt.Errorf("invalid due date %q", value)
+			}
+		}
+		i.Due = d
+	case "rec":
+		r, err := ParseRecurrence(value)
+		if err != nil {
+			return err
+		}
+		i.Recur = r
+	case "pri":
+		p, err := ParsePriority(value)
+		if err != nil {
+			return err
+		}
+		i.Priority = p
+	case "tag":
+		if !i.hasTag(value) {
+			i.Tags = append(i.Tags, value)
+		}
+	case "parent":
+		i.Parent = value
+	case "dep":
+		for _, id := range strings.Split(value, ",") {
+			if id != "" && !slices.Contains(i.BlockedBy, id) {
+				i.BlockedBy = append(i.BlockedBy, id)
+			}
+		}
+	case "note":
+		notes, err := url.QueryUnescape(value)
+		if err != nil {
+			return fmt.Errorf("invalid note %q", value)
+		}
+		i.Notes = notes
+	case "created", "completed":
+		t, err := time.ParseInLocation(todoTxtTime, value, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid %s time %q", key, value)
+		}
+		if key == "created" {
+			i.CreatedAt = t
+		} else {
+			i.CompletedAt = t
+		}
+	case "id":
+		i.ID = value
+	default:
+		i.Extra = append(i.Extra, key+":"+value)
+	}
+	return nil
+}
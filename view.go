@@ -0,0 +1,236 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//=====================
+// VIEWS
+//=====================
+
+// Entry is an item of the List along with it's position (starting at 1) on the List. The item fields are promoted,
+// so e.Task, e.Done, etc. can be used directly.
+// Num is the number of the item on the tree of subtasks and Depth how many parents it has. eg.: 2.1 and 1 for the first
+// subtask of the second top-level item.
+type Entry struct {
+	Pos   int
+	Num   string
+	Depth int
+	item
+}
+
+// View is a selection of the List items. Each Entry keeps the position it has on the List, so sorting or
+// filtering a View doesn't change the numbers used to refer to the items.
+type View []Entry
+
+// View returns a View with every item of the List, in tree order: each item followed by it's subtasks. Lists without
+// subtasks are in the order they're stored, numbered by their position.
+func (l *List) View() View {
+	ls := *l
+	v := make(View, 0, len(ls))
+	added := make([]bool, len(ls))
+
+	// find the parent and the subtasks of every item once, rather than scanning the List for each item. Subtasks
+	// keep the order they have on the List
+	index := ls.indexByID()
+	parents := make([]int, len(ls))
+	children := map[int][]int{}
+	for idx, it := range ls {
+		if p, ok := index[it.Parent]; ok && it.Parent != "" && p != idx {
+			parents[idx] = p + 1
+			children[p+1] = append(children[p+1], idx+1)
+		}
+	}
+
+	// add the item to the View, followed by it's subtasks
+	var add func(pos int, num string, depth int)
+	add = func(pos int, num string, depth int) {
+		added[pos-1] = true
+		v = append(v, Entry{Pos: pos, Num: num, Depth: depth, item: ls[pos-1]})
+
+		n := 0
+		for _, c := range children[pos] {
+			if !added[c-1] {
+				n++
+				add(c, fmt.Sprintf("%s.%d", num, n), depth+1)
+			}
+		}
+	}
+
+	// start from the top-level items. Items on a loop of parents (only possible if the file was edited by hand)
+	// are never reached from the top, so they're shown as top-level items
+	top := 0
+	for idx := range ls {
+		if parents[idx] == 0 {
+			top++
+			add(idx+1, strconv.Itoa(top), 0)
+		}
+	}
+	for idx := range ls {
+		if !added[idx] {
+			top++
+			add(idx+1, strconv.Itoa(top), 0)
+		}
+	}
+	return v
+}
+
+// number returns the number the Entry is displayed with. Entries that aren't part of a tree use their position.
+func (e Entry) number() string {
+	if e.Num == "" {
+		return strconv.Itoa(e.Pos)
+	}
+	return e.Num
+}
+
+// indent returns the indentation of the Entry on the tree. Two spaces per parent.
+func (e Entry) indent() string {
+	return strings.Repeat("  ", e.Depth)
+}
+
+// String will format the View the same way the List is displayed
+func (v View) String() string {
+	formated := ""
+	// add a prefix to be displayed
+	for _, e := range v {
+		prefix := "[ ] "
+		if e.Done {
+			prefix = "[x] "
+		}
+		// update the format. will dispaly the prefix an order number, the priority (if any), a Task name and it's tags.
+		// subtasks are indented under their parent
+		// eg.: [] 1 Buy Stuff, [x] 2 (A) Go Out +fun,   [ ] 2.1: Book a table
+		formated += fmt.Sprintf("%s%s%s: %s%s\n", e.indent(), prefix, e.number(), e.priorityTag(), e.text())
+	}
+	return formated
+}
+
+// Verbose will format the View with the ID, creation, modification and due dates and status of each item, followed by
+// it's notes. now is used to flag overdue items.
+func (v View) Verbose(now time.Time) string {
+	formated := ""
+	for _, e := range v {
+		status := "Active"
+		prefix := "[ ] "
+		if e.Done {
+			status = "Done"
+			prefix = "[x] "
+		}
+		// show the due date, if there's one, after the creation date
+		dueString := ""
+		if !e.Due.IsZero() {
+			dueString = " | Due: " + FormatDue(e.Due)
+			if e.Overdue(now) {
+				dueString += " (overdue)"
+			}
+		}
+		// and how it repeats, if it does
+		if e.Recur != "" {
+			dueString += " | Repeats: " + e.Recur
+		}
+		// and the IDs of the tasks it depends on
+		if len(e.BlockedBy) > 0 {
+			dueString += " | Depends on: " + strings.Join(e.BlockedBy, ", ")
+		}
+		// and when it was last edited, if it ever was
+		modifiedString := ""
+		if !e.ModifiedAt.IsZero() {
+			modifiedString = " | Modified: " + e.ModifiedAt.Format(time.UnixDate)
+		}
+		// and when it was last reopened, if it ever was
+		reopenedString := ""
+		if n := len(e.Reopened); n > 0 {
+			reopenedString = " | Reopened: " + e.Reopened[n-1].ReopenedAt.Format(time.UnixDate)
+		}
+		formated += fmt.Sprintf("%s%s%s: %s%s | ID: %s | Created: %s%s%s%s | Status: %s\n",
+			e.indent(), prefix, e.number(), e.priorityTag(), e.text(), e.ID, e.CreatedAt.Format(time.UnixDate), modifiedString, reopenedString, dueString, status)
+		// the notes go on the lines below, indented
+		formated += e.notesBlock(e.indent() + "    ")
+	}
+	return formated
+}
+
+// notesBlock returns the notes of the Entry, each line starting with indent, or nothing when it has no notes
+func (e Entry) notesBlock(indent string) string {
+	if e.Notes == "" {
+		return ""
+	}
+	block := ""
+	for _, line := range strings.Split(e.Notes, "\n") {
+		block += indent + line + "\n"
+	}
+	return block
+}
+
+// Details will format every field of the Entry that is set, one per line, followed by it's notes. now is used to flag
+// an overdue item.
+func (e Entry) Details(now time.Time) string {
+	status := "Active"
+	if e.Done {
+		status = "Done"
+	}
+
+	fields := [][2]string{
+		{"Task", e.priorityTag() + e.text()},
+		{"Number", e.number()},
+		{"ID", e.ID},
+		{"Status", status},
+		{"Created", e.CreatedAt.Format(time.UnixDate)},
+	}
+	if !e.ModifiedAt.IsZero() {
+		fields = append(fields, [2]string{"Modified", e.ModifiedAt.Format(time.UnixDate)})
+	}
+	if e.Done {
+		fields = append(fields, [2]string{"Completed", e.CompletedAt.Format(time.UnixDate)})
+	}
+	if n := len(e.Reopened); n > 0 {
+		fields = append(fields, [2]string{"Reopened", fmt.Sprintf("%s (%d times)", e.Reopened[n-1].ReopenedAt.Format(time.UnixDate), n)})
+	}
+	if !e.Due.IsZero() {
+		due := FormatDue(e.Due)
+		if e.Overdue(now) {
+			due += " (overdue)"
+		}
+		fields = append(fields, [2]string{"Due", due})
+	}
+	if e.Recur != "" {
+		fields = append(fields, [2]string{"Repeats", e.Recur})
+	}
+	if e.Parent != "" {
+		fields = append(fields, [2]string{"Parent", e.Parent})
+	}
+	if len(e.BlockedBy) > 0 {
+		fields = append(fields, [2]string{"Depends on", strings.Join(e.BlockedBy, ", ")})
+	}
+
+	formated := ""
+	for _, f := range fields {
+		formated += fmt.Sprintf("%-11s %s\n", f[0]+":", f[1])
+	}
+	if e.Notes != "" {
+		formated += "\n" + e.notesBlock("  ")
+	}
+	return formated
+}
+
+// priorityTag returns the priority formated to be displayed before the task. eg.: "(A) "
+func (e Entry) priorityTag() string {
+	if e.Priority == "" {
+		return ""
+	}
+	return "(" + e.Priority + ") "
+}
+
+// SortByPriority returns a copy of the View sorted from the highest to the lowest priority. Items without a
+// priority go last. Items with the same priority keep their order.
+func (v View) SortByPriority() View {
+	sorted := append(View{}, v...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priorityRank(sorted[i].Priority) < priorityRank(sorted[j].Priority)
+	})
+	return sorted
+}